@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/RiemaLabs/modular-indexer-light/internal/apps"
 	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
 )
@@ -12,7 +14,9 @@ var (
 
 // TODO: Medium. Uniform the expression of Bitcoin block height and hash.
 func main() {
-	if err := apps.NewApp(version, gitHash).Command().Execute(); err != nil {
-		logs.Error.Fatalf("failed to execute: %v", err)
+	err := apps.NewApp(version, gitHash).Command().Execute()
+	if err != nil {
+		logs.Error.Printf("failed to execute: %v", err)
 	}
+	os.Exit(apps.ExitCode(err))
 }
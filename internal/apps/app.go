@@ -1,32 +1,47 @@
 package apps
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
 	"os"
-	"strconv"
-	"strings"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
 	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
 	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
 	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/reports"
 	"github.com/RiemaLabs/modular-indexer-light/internal/services"
 	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
 )
 
 type App struct {
 	version, gitHash string
 
-	ConfigPath, DenyListPath, PrivatePath string
-	EnableTest, EnableDAReport            bool
+	ConfigPath, DenyListPath, PrivatePath, PassphrasePath, WatchListPath, ReportQueuePath string
+	EnableTest, EnableDAReport                                                            bool
+
+	// The worker reporting the checkpoints to DA and the sinks, nil if none is enabled.
+	worker *reports.Worker
+
+	// The report key signing the reported checkpoints.
+	signer *btcec.PrivateKey
+
+	// The passphrase of the private key file, nil until it's read.
+	pass []byte
+
+	// The overrides of the config fields, kept to reload the config file.
+	overrides []*configs.Override
 }
 
 func NewApp(version, gitHash string) *App {
@@ -40,7 +55,7 @@ func (a *App) Command() *cobra.Command {
 		Long: `Light Indexer is an essential component of the Nubit Modular Indexer architecture.
 It enables typical users to verify Bitcoin meta-protocols without requiring substantial computing resources.
 This command offers multiple flags to tailor the indexer's functionality according to the user's needs.`,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if a.EnableTest {
 				logs.Info.Println("Test mode enabled")
 			}
@@ -51,182 +66,313 @@ This command offers multiple flags to tailor the indexer's functionality accordi
 				logs.Info.Println("DA report disabled")
 			}
 
-			if err := configs.Init(a.ConfigPath, a.DenyListPath); err != nil {
-				logs.Error.Fatalln("Config failed to initialize:", err)
+			overrides, err := configOverrides(cmd)
+			if err != nil {
+				return &ExitError{Code: ExitConfig, Err: err}
+			}
+			a.overrides = overrides
+			if err := configs.Init(a.ConfigPath, a.DenyListPath, overrides...); err != nil {
+				return exitErrorf(ExitConfig, "config failed to initialize: %v", err)
+			}
+			if err := logs.Init(&configs.C.Log); err != nil {
+				return exitErrorf(ExitConfig, "log failed to initialize: %v", err)
 			}
+			shutdownTracing, err := tracing.Init(context.Background(), &configs.C.Tracing, a.version)
+			if err != nil {
+				return exitErrorf(ExitConfig, "tracing failed to initialize: %v", err)
+			}
+			defer func() {
+				if err := shutdownTracing(context.Background()); err != nil {
+					logs.Error.Printf("Flush spans error: %v", err)
+				}
+			}()
 
-			a.Run()
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				// Restore the default behavior, so a second signal kills the process at once.
+				<-ctx.Done()
+				stop()
+			}()
+			return a.Run(ctx)
 		},
-		Version: fmt.Sprintf("%v (%v)", a.version, a.gitHash),
+		Version:       fmt.Sprintf("%v (%v)", a.version, a.gitHash),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	cmd.PersistentFlags().StringVarP(&a.ConfigPath, "config", "c", "config.json", "path to config file in JSON, YAML or TOML by the extension, empty to configure by the environment variables and flags only")
+	for _, f := range configs.Fields() {
+		cmd.PersistentFlags().String(f.Flag, "", fmt.Sprintf("override %s of the config file (env %s)", f.Path, f.Env))
 	}
-	cmd.Flags().StringVarP(&a.ConfigPath, "config", "c", "config.json", "path to config file")
-	cmd.Flags().StringVar(&a.DenyListPath, "deny", "deny.jsonlines", "path to deny list file")
-	cmd.Flags().StringVar(&a.PrivatePath, "private", "private", "path to private file")
+	cmd.PersistentFlags().StringVar(&a.DenyListPath, "deny", "deny.jsonlines", "path to deny list file")
+	cmd.PersistentFlags().StringVar(&a.PrivatePath, "private", "private", "path to private file")
+	cmd.PersistentFlags().StringVar(&a.PassphrasePath, "passphrase-file", "", fmt.Sprintf("path to the file of the passphrase of the private file, if %s is unset", EnvPassphrase))
+	cmd.Flags().StringVar(&a.WatchListPath, "watchlist", "watchlist.json", "path to the balance watch list file")
 	cmd.Flags().BoolVarP(&a.EnableTest, "test", "t", false, "Enable this flag to hijack the block height to test the service")
 	cmd.Flags().BoolVarP(&a.EnableDAReport, "report", "", true, "Enable this flag to upload verified checkpoint to DA")
+	cmd.Flags().StringVar(&a.ReportQueuePath, "report-queue", "report-queue.json", "path to the queue file of the checkpoints to report to DA and the sinks")
+	cmd.AddCommand(a.configCommand(), a.denyCommand(), a.verifyEvidenceCommand(), a.namespaceCommand(), a.keyCommand())
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &ExitError{Code: ExitConfig, Err: fmt.Errorf("%v\n\n%s", err, cmd.UsageString())}
+	})
 	return cmd
 }
 
-func (a *App) Run() {
-	a.initDaReport()
-	btcutl.Init(configs.C.Verification.BitcoinRPC)
-
-	logs.Info.Println("Syncing the latest state from committee indexers, please wait...")
-
-	currentBlockHeight, err := btcutl.BTC.GetLatestBlockHeight(context.Background())
-	if err != nil {
-		logs.Error.Fatalf("Failed to get latest block height: %v", err)
+// Run syncs the initial state, then serves the APIs and keeps syncing until the context is done. On shutdown it
+// drains the in-flight requests and aborts the checkpoint fetches, RPC calls and the report in progress, the aborted and
+// pending reports are kept in the report queue for the next run.
+func (a *App) Run(ctx context.Context) error {
+	if err := a.initDaReport(ctx); err != nil {
+		return err
 	}
-	lastBlockHeight := currentBlockHeight - 1
-	lastBlockHash, err := btcutl.BTC.GetBlockHash(context.Background(), lastBlockHeight)
-	if err != nil {
-		logs.Error.Fatalf("Failed to get last block hash: height=%d, err=%v", lastBlockHeight, err)
+	if err := btcutl.Init(configs.C.Verification.BitcoinRPC); err != nil {
+		return exitErrorf(ExitConfig, "failed to initialize Bitcoin RPC client: %v", err)
 	}
 
-	var providers []checkpoints.CheckpointProvider
-	if raw := configs.C.CommitteeIndexers.Raw; a.EnableTest && len(raw) > 0 {
-		for _, sourceRaw := range raw {
-			providers = append(providers, sourceRaw)
-		}
-	} else {
-		for _, sourceS3 := range configs.C.CommitteeIndexers.S3 {
-			providers = append(providers, checkpoints.NewProviderS3(&sourceS3, configs.C.Verification.MetaProtocol))
-		}
-		for _, sourceDA := range configs.C.CommitteeIndexers.DA {
-			providers = append(providers, checkpoints.NewProviderDA(&sourceDA, configs.C.Verification.MetaProtocol))
-		}
-	}
+	providers := a.providers(configs.C)
 	actual := len(providers)
 	expected := configs.C.Verification.MinimalCheckpoint
 	if actual < expected {
-		logs.Error.Fatalf("Insufficient checkpoint providers: actual=%d, expected=%d", actual, expected)
+		return exitErrorf(ExitConfig, "insufficient checkpoint providers: actual=%d, expected=%d", actual, expected)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-	cps, err := checkpoints.GetCheckpoints(ctx, providers, lastBlockHeight, lastBlockHash)
+	logs.Info.Println("Syncing the latest state from committee indexers, please wait...")
+	lastCheckpoint, err := states.Bootstrap(ctx, btcutl.BTC, providers, states.DefaultFetchTimeout)
 	if err != nil {
-		logs.Error.Fatalf("Failed to get checkpoints: height=%d, hash=%s, err=%v", lastBlockHeight, lastBlockHash, err)
-	}
-
-	// TODO: Historical verification.
-	if inconsistent := checkpoints.Inconsistent(cps); inconsistent {
-		logs.Error.Fatalf("inconsistent checkpoints detected at height %q, historical verification is not supported but will be released soon :'(", lastBlockHeight)
+		return &ExitError{Code: ExitUnavailable, Err: err}
 	}
 	logs.Info.Println("Latest state successfully synced!")
 
 	states.Init(
+		btcutl.BTC,
 		a.DenyListPath,
 		providers,
-		cps[0],
+		lastCheckpoint,
 		configs.C.Verification.MinimalCheckpoint,
-		2*time.Minute,
+		states.DefaultFetchTimeout,
+		configs.C.Verification.HistorySize,
 	)
 
-	go services.StartService(a.EnableTest, configs.C.ListenAddr)
-	a.runSyncForever()
+	services.InitDenyList(a.DenyListPath)
+	if err := a.initReport(); err != nil {
+		return err
+	}
+	// Validated with the config.
+	allow, _ := configs.C.Admin.WebhookAllowPrefixes()
+	if err := services.InitWatchList(a.WatchListPath, allow); err != nil {
+		return exitErrorf(ExitConfig, "failed to read watch list: %v", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		services.RunWatcher(ctx, services.W)
+	}()
+	go func() {
+		defer wg.Done()
+		if err := services.StartService(ctx, a.EnableTest, configs.C.ListenAddr, &configs.C.Admin); err != nil {
+			cancel(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		a.runSyncForever(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		a.watchReload(ctx)
+	}()
+	if a.worker != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.worker.Run(ctx)
+		}()
+	}
+	if p := a.fraudPublisher(); p.Enabled() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reports.RunFraudPublisher(ctx, p, configs.C.Report.Name, configs.C.Verification.MetaProtocol, a.version)
+		}()
+	}
+
+	<-ctx.Done()
+	cause := context.Cause(ctx)
+	logs.Info.Printf("Shutting down: %v", cause)
+
+	// End the event streams, so the long-lived requests don't block the draining.
+	states.S.Close()
+	wg.Wait()
+
+	if !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	logs.Info.Println("Light indexer stopped")
+	return nil
 }
 
-func (a *App) initDaReport() {
+// providers creates the checkpoint providers of the committee indexers, the raw checkpoints are only used in the test
+// mode.
+func (a *App) providers(c *configs.Config) []checkpoints.CheckpointProvider {
+	var providers []checkpoints.CheckpointProvider
+	if raw := c.CommitteeIndexers.Raw; a.EnableTest && len(raw) > 0 {
+		for _, sourceRaw := range raw {
+			providers = append(providers, sourceRaw)
+		}
+		return providers
+	}
+	for _, sourceS3 := range c.CommitteeIndexers.S3 {
+		p := checkpoints.NewProviderS3(&sourceS3, c.Verification.MetaProtocol)
+		p.Signers = c.Verification.Signers
+		providers = append(providers, p)
+	}
+	for _, sourceDA := range c.CommitteeIndexers.DA {
+		providers = append(providers, checkpoints.NewProviderDA(&sourceDA, c.Verification.MetaProtocol))
+	}
+	return providers
+}
+
+func (a *App) initDaReport(ctx context.Context) error {
 	if !a.EnableDAReport {
-		return
+		return nil
 	}
 
-	if err := configs.C.Report.LoadPrivate(a.PrivatePath); err != nil {
-		logs.Error.Fatalln("Failed to read private key:", err)
+	if err := configs.C.Report.Validate(); err != nil {
+		return &ExitError{Code: ExitConfig, Err: err}
+	}
+	if err := configs.C.Report.LoadPrivate(a.PrivatePath, a.passphrase); err != nil {
+		return exitErrorf(ExitConfig, "failed to read private key: %v", err)
 	}
 
 	if !checkpoint.IsValidNamespaceID(configs.C.Report.NamespaceID) {
-		logs.Info.Println("Invalid Namespace ID found in configurations. Initializing a new namespace.")
-		scanner := bufio.NewScanner(os.Stdin)
-		namespaceName := ""
-		for {
-			fmt.Print("Please enter your desired namespace name:")
-			if scanner.Scan() {
-				namespaceName = scanner.Text()
-				if strings.TrimSpace(namespaceName) == "" {
-					fmt.Print("Namespace name required!")
-				} else {
-					break
-				}
+		name := configs.C.Report.NamespaceName
+		if name == "" {
+			if !term.IsTerminal(int(os.Stdin.Fd())) {
+				return exitErrorf(
+					ExitConfig,
+					"namespace ID required: set report.namespaceID or report.namespaceName, or run `namespace create`",
+				)
+			}
+			logs.Info.Println("Invalid Namespace ID found in configurations. Initializing a new namespace.")
+			var err error
+			if name, err = promptNamespaceName(os.Stdin, os.Stdout); err != nil {
+				return &ExitError{Code: ExitConfig, Err: err}
 			}
 		}
-		nid, err := checkpoint.CreateNamespace(
-			configs.C.Report.PrivateKey,
-			configs.C.Report.GasCoupon,
-			namespaceName,
-			configs.C.Report.Network,
-		)
+		nid, err := createNamespace(ctx, &configs.C.Report, name)
 		if err != nil {
-			logs.Error.Fatalf("failed to create namespace: %v", err)
+			return err
 		}
 		configs.C.Report.NamespaceID = nid
-		data, err := json.MarshalIndent(configs.C, "", "  ")
-		if err != nil {
-			logs.Error.Fatalln("marshal configurations error:", err)
+		if err := a.saveNamespaceID(nid); err != nil {
+			return err
 		}
-		err = os.WriteFile(a.ConfigPath, data, 0644)
-		if err != nil {
-			logs.Error.Fatalf("Failed to save namespace ID to configuration file: %v", err)
-		}
-		logs.Info.Printf("Namespace created successfully: %s", nid)
 	}
+	return nil
 }
 
-func (a *App) runSyncForever() {
+// runSyncForever syncs the checkpoints of new blocks until the context is done.
+func (a *App) runSyncForever(ctx context.Context) {
 	for {
-		time.Sleep(10 * time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(states.DefaultSyncInterval):
+		}
 		logs.Info.Println("Syncing latest state...")
 
-		currentHeight, err := btcutl.BTC.GetLatestBlockHeight(context.Background())
+		updated, err := states.S.Sync(ctx)
 		if err != nil {
-			logs.Error.Printf("Failed to get latest block height: %v", err)
+			logs.Error.Printf("Failed to sync latest state: %v", err)
 			continue
 		}
-		currentHash, err := btcutl.BTC.GetBlockHash(context.Background(), currentHeight)
-		if err != nil {
-			logs.Error.Printf("Failed to get block hash: %v", err)
-			continue
+		if updated && a.worker != nil {
+			ck, err := a.newCheckpoint()
+			if err != nil {
+				logs.Error.Printf("Failed to sign the checkpoint: %v", err)
+				continue
+			}
+			if n, err := a.worker.Queue.Push(ck, a.worker.Names(), configs.C.Report.Jitter.Duration); err != nil {
+				logs.Error.Printf("Failed to save the report queue: %v", err)
+			} else if n > 0 {
+				logs.Debug.Printf("Checkpoint queued for report: height=%s, reports=%d", ck.Height, n)
+			}
 		}
 
-		if first := states.S.CurrentFirstCheckpoint(); first == nil ||
-			first.Checkpoint.Height != strconv.Itoa(int(currentHeight)) ||
-			first.Checkpoint.Hash != currentHash {
-			// Checkpoints are not the latest, start syncing.
+		logs.Info.Printf("Listening for new Bitcoin block: height=%d", states.S.CurrentHeight())
+	}
+}
 
-			if err := states.S.UpdateCheckpoints(currentHeight, currentHash); err != nil {
-				logs.Error.Printf("Failed to update checkpoints: %v", err)
-				continue
-			}
+// newCheckpoint returns the checkpoint to report of the current verified one, signed by the report key.
+func (a *App) newCheckpoint() (*checkpoints.SignedCheckpoint, error) {
+	cp := states.S.CurrentFirstCheckpoint().Checkpoint
+	return checkpoints.Sign(&checkpoint.Checkpoint{
+		Commitment:   cp.Commitment,
+		Hash:         cp.Hash,
+		Height:       cp.Height,
+		MetaProtocol: configs.C.Verification.MetaProtocol,
+		Name:         configs.C.Report.Name,
+		Version:      a.version,
+	}, a.signer)
+}
 
-			if a.EnableDAReport {
-				cp := states.S.CurrentFirstCheckpoint().Checkpoint
-				newCp := checkpoint.Checkpoint{
-					Commitment:   cp.Commitment,
-					Hash:         cp.Hash,
-					Height:       cp.Height,
-					MetaProtocol: configs.C.Verification.MetaProtocol,
-					Name:         configs.C.Report.Name,
-					Version:      a.version,
-				}
+// initReport creates the reporters of DA and the sinks, and the worker reporting the checkpoints of the persistent
+// queue to them. There is no worker if none is enabled.
+func (a *App) initReport() error {
+	var rs []reports.Reporter
+	if a.EnableDAReport {
+		rs = append(rs, reports.NewDA(&configs.C.Report))
+	}
+	sinks, err := reports.NewReporters(&configs.C.Sinks)
+	if err != nil {
+		return exitErrorf(ExitConfig, "invalid sinks: %v", err)
+	}
+	rs = append(rs, sinks...)
+	if len(rs) == 0 {
+		return nil
+	}
 
-				time.Sleep(time.Duration(rand.Intn(40)+1) * time.Second)
-
-				if err := checkpoint.UploadCheckpointByDA(
-					&newCp,
-					configs.C.Report.PrivateKey,
-					configs.C.Report.GasCoupon,
-					configs.C.Report.NamespaceID,
-					configs.C.Report.Network,
-					configs.C.Report.Timeout.Duration,
-				); err != nil {
-					logs.Error.Printf("Unable to upload the checkpoint via DA: %v", err)
-				} else {
-					logs.Info.Printf("Checkpoint successfully uploaded via DA at height: %s", newCp.Height)
-				}
-			}
+	// The sinks sign the checkpoints with the report key as well, which is loaded only if the DA report is enabled.
+	if configs.C.Report.PrivateKey == "" {
+		if err := configs.C.Report.LoadPrivate(a.PrivatePath, a.passphrase); err != nil {
+			return exitErrorf(ExitConfig, "failed to read private key: %v", err)
 		}
+	}
+	if a.signer, err = checkpoints.ParsePrivateKey(configs.C.Report.PrivateKey); err != nil {
+		return exitErrorf(ExitConfig, "failed to read private key: %v", err)
+	}
+	logs.Info.Printf("Reported checkpoints are signed by: %s", checkpoints.PublicKey(a.signer))
 
-		logs.Info.Printf("Listening for new Bitcoin block: height=%d", states.S.CurrentHeight())
+	q, err := reports.OpenQueue(a.ReportQueuePath)
+	if err != nil {
+		return exitErrorf(ExitConfig, "failed to read report queue: %v", err)
+	}
+	services.InitReportQueue(q)
+	a.worker = &reports.Worker{
+		Queue:      q,
+		Reporters:  rs,
+		Jitter:     configs.C.Report.Jitter.Duration,
+		MaxBackoff: configs.C.Report.MaxBackoff.Duration,
+	}
+	return nil
+}
+
+// configOverrides returns the overrides of the config fields, from the LIGHT_* environment variables then the flags, so
+// the flags take precedence.
+func configOverrides(cmd *cobra.Command) ([]*configs.Override, error) {
+	overrides, err := configs.EnvOverrides(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range configs.Fields() {
+		if flag := cmd.Flags().Lookup(f.Flag); flag != nil && flag.Changed {
+			overrides = append(overrides, &configs.Override{Path: f.Path, Value: flag.Value.String()})
+		}
 	}
+	return overrides, nil
 }
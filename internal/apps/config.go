@@ -0,0 +1,109 @@
+package apps
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+)
+
+// DefaultProbeTimeout is the timeout of probing one source by `config check`.
+const DefaultProbeTimeout = 30 * time.Second
+
+func (a *App) configCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration file.",
+	}
+
+	var offline, report bool
+	check := &cobra.Command{
+		Use:   "check",
+		Short: "Validate the configuration file and probe the reachability of every source.",
+		Long: `Validate the configuration file, then probe the Bitcoin RPC and fetch the checkpoint of the last block from
+every committee indexer. It fails if the configuration is invalid, or fewer committee indexers than
+verification.minimalCheckpoint are reachable.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			c, err := a.readConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if report {
+				if err := c.Report.Validate(); err != nil {
+					return &ExitError{Code: ExitConfig, Err: err}
+				}
+			}
+			if a.ConfigPath == "" {
+				_, _ = fmt.Fprintln(out, "Configuration is valid.")
+			} else {
+				_, _ = fmt.Fprintf(out, "Configuration %s is valid.\n", a.ConfigPath)
+			}
+			if offline {
+				return nil
+			}
+			return probe(cmd.Context(), out, c)
+		},
+	}
+	check.Flags().BoolVar(&offline, "offline", false, "only validate the configuration without probing the sources")
+	check.Flags().BoolVar(&report, "report", true, "also validate the report section for uploading checkpoints to DA")
+	cmd.AddCommand(check)
+	return cmd
+}
+
+// probe checks the Bitcoin RPC and fetches the checkpoint of the last block from every committee indexer.
+func probe(ctx context.Context, out io.Writer, c *configs.Config) error {
+	btc, err := btcutl.New(c.Verification.BitcoinRPC)
+	if err != nil {
+		return exitErrorf(ExitConfig, "invalid Bitcoin RPC: %v", err)
+	}
+	rpcCtx, cancel := context.WithTimeout(ctx, DefaultProbeTimeout)
+	defer cancel()
+	tip, err := btc.GetLatestBlockHeight(rpcCtx)
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "FAIL  bitcoinRPC  %v\n", err)
+		return exitErrorf(ExitUnavailable, "Bitcoin RPC unreachable")
+	}
+	height := tip - 1
+	hash, err := btc.GetBlockHash(rpcCtx, height)
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "FAIL  bitcoinRPC  %v\n", err)
+		return exitErrorf(ExitUnavailable, "Bitcoin RPC unreachable")
+	}
+	_, _ = fmt.Fprintf(out, "OK    bitcoinRPC  tip=%d\n", tip)
+
+	var providers []checkpoints.CheckpointProvider
+	for i := range c.CommitteeIndexers.S3 {
+		p := checkpoints.NewProviderS3(&c.CommitteeIndexers.S3[i], c.Verification.MetaProtocol)
+		p.Signers = c.Verification.Signers
+		providers = append(providers, p)
+	}
+	for i := range c.CommitteeIndexers.DA {
+		providers = append(providers, checkpoints.NewProviderDA(&c.CommitteeIndexers.DA[i], c.Verification.MetaProtocol))
+	}
+	reachable := 0
+	for _, p := range providers {
+		pCtx, cancel := context.WithTimeout(ctx, DefaultProbeTimeout)
+		ck, err := p.Get(pCtx, height, hash)
+		cancel()
+		if err != nil {
+			_, _ = fmt.Fprintf(out, "FAIL  %s  %v\n", checkpoints.ProviderName(p), err)
+			continue
+		}
+		reachable++
+		_, _ = fmt.Fprintf(out, "OK    %s  height=%d, commitment=%s\n", checkpoints.ProviderName(p), height, ck.Checkpoint.Commitment)
+	}
+
+	if expected := c.Verification.MinimalCheckpoint; reachable < expected {
+		return exitErrorf(ExitUnavailable, "insufficient reachable committee indexers: actual=%d, expected=%d", reachable, expected)
+	}
+	_, _ = fmt.Fprintf(out, "%d of %d committee indexers reachable.\n", reachable, len(providers))
+	return nil
+}
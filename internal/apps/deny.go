@@ -0,0 +1,198 @@
+package apps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/services"
+)
+
+func (a *App) denyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deny",
+		Short: "Manage the deny list of committee indexers.",
+		Long: `Manage the deny list of committee indexers. A running Light Indexer reloads the deny list on change, so the
+denied committee indexers are excluded, and the removed or expired ones are back from the next block.`,
+	}
+
+	var asJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the entries of the deny list.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := a.readConfig(cmd)
+			if err != nil {
+				return err
+			}
+			items, err := configs.ReadDenyList(a.DenyListPath)
+			if err != nil {
+				return err
+			}
+			entries := services.NewDenyListEntries(items, c.Verification.DenyExpiry.Duration)
+			if asJSON {
+				return printJSON(cmd.OutOrStdout(), entries)
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tHEIGHT\tCREATED\tEXPIRES\tREASON")
+			for _, e := range entries {
+				height, created, expires := "-", "-", "never"
+				if e.Evidence != nil {
+					height = fmt.Sprint(e.Evidence.Height)
+				}
+				if !e.CreatedAt.IsZero() {
+					created = e.CreatedAt.Format(time.RFC3339)
+				}
+				if e.Expired {
+					expires = "expired"
+				} else if e.ExpiresAt != nil {
+					expires = e.ExpiresAt.Format(time.RFC3339)
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Name, height, created, expires, e.Reason)
+			}
+			return w.Flush()
+		},
+	}
+	list.Flags().BoolVar(&asJSON, "json", false, "print the entries in JSON")
+
+	var reason string
+	add := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Deny the committee indexer of the name in the configuration file.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := a.readConfig(cmd)
+			if err != nil {
+				return err
+			}
+			d, err := c.CommitteeIndexers.NewDenial(args[0], reason)
+			if err != nil {
+				return &ExitError{Code: ExitConfig, Err: err}
+			}
+			if err := configs.AppendDenyList(a.DenyListPath, d); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Committee indexer %s denied.\n", args[0])
+			return nil
+		},
+	}
+	add.Flags().StringVar(&reason, "reason", "", "the reason to deny the committee indexer")
+
+	var expired bool
+	remove := &cobra.Command{
+		Use:   "remove [NAME...]",
+		Short: "Remove the entries of the committee indexers, or the expired ones with --expired.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !expired {
+				return exitErrorf(ExitConfig, "either committee indexer names or --expired is required")
+			}
+			c, err := a.readConfig(cmd)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			removed, err := configs.RemoveDenyList(a.DenyListPath, func(d *configs.DenyList) bool {
+				return slices.Contains(args, d.Name()) || (expired && d.Expired(c.Verification.DenyExpiry.Duration, now))
+			})
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%d entries removed.\n", len(removed))
+			return nil
+		},
+	}
+	remove.Flags().BoolVar(&expired, "expired", false, "remove the expired entries")
+
+	verify := &cobra.Command{
+		Use:   "verify [NAME...]",
+		Short: "Re-verify the evidence of the entries against Bitcoin and the committee indexers.",
+		Long: `Re-verify the evidence of the entries, or only those of the named committee indexers: the block hash against
+the Bitcoin RPC, the checkpoint still served by the denied committee indexer, and the checkpoints of the other
+committee indexers in the configuration file. It fails if any evidence is refuted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := a.readConfig(cmd)
+			if err != nil {
+				return err
+			}
+			items, err := configs.ReadDenyList(a.DenyListPath)
+			if err != nil {
+				return err
+			}
+			btc, err := btcutl.New(c.Verification.BitcoinRPC)
+			if err != nil {
+				return exitErrorf(ExitConfig, "invalid Bitcoin RPC: %v", err)
+			}
+
+			var reports []*checkpoints.DenialReport
+			for _, d := range items {
+				if len(args) > 0 && !slices.Contains(args, d.Name()) {
+					continue
+				}
+				ctx, cancel := context.WithTimeout(cmd.Context(), services.DefaultDenialVerifyTimeout)
+				reports = append(reports, checkpoints.VerifyDenial(ctx, btc, d, a.providers(c), c.Verification.MetaProtocol))
+				cancel()
+			}
+
+			return printReports(cmd.OutOrStdout(), reports, asJSON)
+		},
+	}
+	verify.Flags().BoolVar(&asJSON, "json", false, "print the reports in JSON")
+
+	cmd.AddCommand(list, add, remove, verify, a.denyImportCommand())
+	return cmd
+}
+
+// readConfig reads the configuration file with the overrides, without removing the denied committee indexers.
+func (a *App) readConfig(cmd *cobra.Command) (*configs.Config, error) {
+	overrides, err := configOverrides(cmd)
+	if err != nil {
+		return nil, &ExitError{Code: ExitConfig, Err: err}
+	}
+	c, err := configs.ReadConfig(a.ConfigPath, overrides...)
+	if err != nil {
+		return nil, &ExitError{Code: ExitConfig, Err: err}
+	}
+	return c, nil
+}
+
+// printReports prints the reports, and fails if any evidence is refuted.
+func printReports(out io.Writer, reports []*checkpoints.DenialReport, asJSON bool) error {
+	if asJSON {
+		if err := printJSON(out, reports); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range reports {
+			_, _ = fmt.Fprintf(out, "%s  height=%d  %s\n", r.Name, r.Height, r.Verdict)
+			for _, c := range r.Checks {
+				status := "OK  "
+				if !c.OK {
+					status = "FAIL"
+				}
+				_, _ = fmt.Fprintf(out, "  %s  %s  %s\n", status, c.Name, c.Message)
+			}
+		}
+	}
+	for _, r := range reports {
+		if r.Verdict == checkpoints.VerdictRefuted {
+			return exitErrorf(ExitFailure, "evidence of %s refuted", r.Name)
+		}
+	}
+	return nil
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
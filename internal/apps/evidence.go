@@ -0,0 +1,113 @@
+package apps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/services"
+)
+
+func (a *App) verifyEvidenceCommand() *cobra.Command {
+	var (
+		bitcoin bool
+		asJSON  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "verify-evidence FILE...",
+		Short: "Re-check fraud proofs offline.",
+		Long: `Re-check the fraud proofs in the files, "-" for the standard input. A file holds fraud proofs or deny list
+entries in JSON, one after another, e.g. the deny list itself. The state proof of each fraud proof is applied to
+its previous commitment, which must not produce the claimed commitment. With --bitcoin, the block hash and the
+Ordinals transfers are verified against the Bitcoin RPC of the configuration as well. It fails if any fraud proof
+is refuted. Nothing in a fraud proof shows the accused committee indexer served its state proof, so the verdict is only
+as trustworthy as the origin of the file, and a state proof that couldn't be applied at all is inconclusive.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var btc *btcutl.Client
+			if bitcoin {
+				c, err := a.readConfig(cmd)
+				if err != nil {
+					return err
+				}
+				if btc, err = btcutl.New(c.Verification.BitcoinRPC); err != nil {
+					return exitErrorf(ExitConfig, "invalid Bitcoin RPC: %v", err)
+				}
+			}
+
+			var reports []*checkpoints.DenialReport
+			for _, path := range args {
+				proofs, err := readFraudProofs(cmd.InOrStdin(), path)
+				if err != nil {
+					return &ExitError{Code: ExitConfig, Err: err}
+				}
+				for _, p := range proofs {
+					ctx, cancel := context.WithTimeout(cmd.Context(), services.DefaultDenialVerifyTimeout)
+					reports = append(reports, checkpoints.VerifyFraudProof(ctx, btc, p))
+					cancel()
+				}
+			}
+			if len(reports) == 0 {
+				return exitErrorf(ExitConfig, "no fraud proofs found")
+			}
+			return printReports(cmd.OutOrStdout(), reports, asJSON)
+		},
+	}
+	cmd.Flags().BoolVar(&bitcoin, "bitcoin", false, "also verify the block hash and the Ordinals transfers against Bitcoin")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the reports in JSON")
+	return cmd
+}
+
+// readFraudProofs reads the fraud proofs of the file, skipping the deny list entries without one.
+func readFraudProofs(stdin io.Reader, path string) ([]*configs.FraudProof, error) {
+	r := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+
+	var ret []*configs.FraudProof
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return ret, nil
+			}
+			return nil, fmt.Errorf("invalid JSON: path=%s, err=%v", path, err)
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("invalid fraud proof: path=%s, err=%v", path, err)
+		}
+		if _, ok := fields["evidence"]; ok {
+			d := new(configs.DenyList)
+			if err := json.Unmarshal(raw, d); err != nil {
+				return nil, fmt.Errorf("invalid deny list entry: path=%s, err=%v", path, err)
+			}
+			if d.Evidence != nil && d.Evidence.Proof != nil {
+				ret = append(ret, d.Evidence.Proof)
+			}
+			continue
+		}
+
+		p := new(configs.FraudProof)
+		if err := json.Unmarshal(raw, p); err != nil {
+			return nil, fmt.Errorf("invalid fraud proof: path=%s, err=%v", path, err)
+		}
+		ret = append(ret, p)
+	}
+}
@@ -0,0 +1,46 @@
+package apps
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes of the process.
+const (
+	ExitOK = 0
+
+	// ExitFailure means the light indexer failed while running.
+	ExitFailure = 1
+
+	// ExitConfig means the flags, the configurations or the files they refer to are invalid.
+	ExitConfig = 2
+
+	// ExitUnavailable means the initial state could not be synced from the Bitcoin RPC or the committee indexers.
+	ExitUnavailable = 3
+)
+
+// ExitError is an error with the exit code of the process.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+func exitErrorf(code int, format string, a ...any) error {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// ExitCode returns the exit code of the error returned by the command, nil means ExitOK.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var e *ExitError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ExitFailure
+}
@@ -0,0 +1,119 @@
+package apps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/reports"
+	"github.com/RiemaLabs/modular-indexer-light/internal/services"
+)
+
+// fraudPublisher returns the publisher of the fraud reports, which uploads to DA only if the DA report is enabled.
+func (a *App) fraudPublisher() *reports.FraudPublisher {
+	p := &reports.FraudPublisher{Config: &configs.C.Fraud, BTC: btcutl.BTC}
+	if a.EnableDAReport {
+		p.Report = &configs.C.Report
+	} else if configs.C.Fraud.NamespaceID != "" {
+		logs.Warn.Println("Fraud reports are not published to DA with the DA report disabled")
+	}
+	return p
+}
+
+func (a *App) denyImportCommand() *cobra.Command {
+	var (
+		namespaces []string
+		asJSON     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "import [FILE...]",
+		Short: "Import the fraud reports published by other Light Indexers.",
+		Long: `Import the fraud reports in the files, "-" for the standard input, or without files those published to the DA
+namespaces of fraud.import. Only the reports of the committee indexers in the configuration file are imported, after
+the previous commitment is checked against the committee indexers and the fraud proof against the Bitcoin RPC.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := a.readConfig(cmd)
+			if err != nil {
+				return err
+			}
+			btc, err := btcutl.New(c.Verification.BitcoinRPC)
+			if err != nil {
+				return exitErrorf(ExitConfig, "invalid Bitcoin RPC: %v", err)
+			}
+
+			var frauds []*reports.FraudReport
+			for _, path := range args {
+				r := cmd.InOrStdin()
+				if path != "-" {
+					f, err := os.Open(path)
+					if err != nil {
+						return &ExitError{Code: ExitConfig, Err: err}
+					}
+					defer func() { _ = f.Close() }()
+					r = f
+				}
+				items, err := reports.ParseFraudReports(r)
+				if err != nil {
+					return exitErrorf(ExitConfig, "invalid fraud reports: path=%s, err=%v", path, err)
+				}
+				frauds = append(frauds, items...)
+			}
+			if len(args) == 0 {
+				if len(namespaces) == 0 {
+					namespaces = c.Fraud.Import
+				}
+				if len(namespaces) == 0 {
+					return exitErrorf(ExitConfig, "either files, --namespace or fraud.import is required")
+				}
+				for _, nid := range namespaces {
+					data, _, err := reports.ReadDA(cmd.Context(), c.Report.Network, nid, 0)
+					if err != nil {
+						return &ExitError{Code: ExitUnavailable, Err: err}
+					}
+					for _, d := range data {
+						items, err := reports.ParseFraudReports(bytes.NewReader(d))
+						if err != nil {
+							logs.Warn.With("namespaceID", nid).Printf("Invalid fraud report: %v", err)
+							continue
+						}
+						frauds = append(frauds, items...)
+					}
+				}
+			}
+
+			im := &reports.Importer{
+				BTC:          btc,
+				DenyListPath: a.DenyListPath,
+				Indexers:     &c.CommitteeIndexers,
+				Providers:    a.providers(c),
+			}
+			var results []*reports.ImportResult
+			for _, r := range frauds {
+				ctx, cancel := context.WithTimeout(cmd.Context(), services.DefaultDenialVerifyTimeout)
+				results = append(results, im.Import(ctx, r))
+				cancel()
+			}
+
+			out := cmd.OutOrStdout()
+			if asJSON {
+				return printJSON(out, results)
+			}
+			w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tHEIGHT\tREPORTER\tRESULT\tMESSAGE")
+			for _, r := range results {
+				_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", r.Name, r.Height, r.Reporter, r.Result, r.Message)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringSliceVar(&namespaces, "namespace", nil, "the DA namespace IDs to import from instead of fraud.import")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the results in JSON")
+	return cmd
+}
@@ -0,0 +1,276 @@
+package apps
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/keystore"
+	"github.com/RiemaLabs/modular-indexer-light/internal/utils"
+	"github.com/RiemaLabs/modular-indexer-light/internal/wallet"
+)
+
+// Formats of the exported private key.
+const (
+	KeyFormatMnemonic = "mnemonic"
+	KeyFormatWallet   = "wallet"
+	KeyFormatHex      = "hex"
+)
+
+func (a *App) keyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "key",
+		Short: "Manage the private key reporting and signing the checkpoints.",
+	}
+
+	generate := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a private key from a new wallet and print its mnemonic.",
+		Long: `Generate a private key from a new wallet, save it to the private file and print the mnemonic of the wallet,
+which restores the key by "key import-mnemonic". It fails if the private file exists, use "key rotate" to replace it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.checkNoPrivate(); err != nil {
+				return err
+			}
+			return a.generateKey(cmd.OutOrStdout(), cmd.ErrOrStderr())
+		},
+	}
+
+	var force, fromWallet bool
+	importMnemonic := &cobra.Command{
+		Use:   "import-mnemonic",
+		Short: "Restore the private key from the mnemonic read from stdin.",
+		Long: `Restore the private key from the mnemonic printed by "key generate" or "key export", read from stdin, and
+save it to the private file. With --wallet the input is the wallet backup of "key export --format wallet" instead, and
+the passphrase must be the one of the exported private file. It fails if the private file exists, unless --force moves
+it aside first.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !force {
+				if err := a.checkNoPrivate(); err != nil {
+					return err
+				}
+			}
+			line, err := readSecret(cmd.InOrStdin(), cmd.ErrOrStderr(), fromWallet)
+			if err != nil {
+				return exitErrorf(ExitConfig, "%v", err)
+			}
+			pass, err := a.passphrase(true)
+			if err != nil {
+				return exitErrorf(ExitConfig, "%v", err)
+			}
+			pwd := string(pass)
+
+			var w *wallet.Wallet
+			if fromWallet {
+				if w, err = wallet.ImportBase64(line, &pwd); err != nil {
+					return exitErrorf(ExitConfig, "invalid wallet backup: %v", err)
+				}
+			} else if w, err = wallet.ImportMnemonic(strings.Fields(line), &pwd); err != nil {
+				return exitErrorf(ExitConfig, "%v", err)
+			}
+			key, backup, err := configs.WalletPrivate(w, pwd)
+			if err != nil {
+				return err
+			}
+
+			if force {
+				if err := a.movePrivate(cmd.ErrOrStderr()); err != nil {
+					return err
+				}
+			}
+			if err := configs.WritePrivate(a.PrivatePath, key, backup, a.passphrase); err != nil {
+				return fmt.Errorf("write private key to file error: %v", err)
+			}
+			return printKeyIdentity(cmd.OutOrStdout(), key)
+		},
+	}
+	importMnemonic.Flags().BoolVar(&force, "force", false, "move the existing private file aside instead of failing")
+	importMnemonic.Flags().BoolVar(&fromWallet, "wallet", false, "read the wallet backup in base64 instead of the mnemonic")
+
+	var format string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Print the mnemonic, the wallet backup or the private key to back up.",
+		Long: fmt.Sprintf(`Print the private key in the format: %q for the mnemonic of the wallet it's derived from, %q for the
+wallet backup in base64, or %q for the raw private key. Keys generated by the previous versions have no wallet, so they
+could only be exported in hex.`, KeyFormatMnemonic, KeyFormatWallet, KeyFormatHex),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, backup, err := configs.ReadPrivateWallet(a.PrivatePath, a.passphrase)
+			if err != nil {
+				return exitErrorf(ExitConfig, "failed to read private key: %v", err)
+			}
+			if format == KeyFormatHex {
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), key)
+				return err
+			}
+			if format != KeyFormatMnemonic && format != KeyFormatWallet {
+				return exitErrorf(ExitConfig, "unknown format: %s", format)
+			}
+			if backup == nil {
+				return exitErrorf(ExitConfig, "the private key isn't derived from a wallet, export it with --format %s", KeyFormatHex)
+			}
+			pass, err := a.passphrase(false)
+			if err != nil {
+				return exitErrorf(ExitConfig, "%v", err)
+			}
+			pwd := string(pass)
+			w, err := wallet.ImportBinary(backup, &pwd)
+			if err != nil {
+				return fmt.Errorf("read wallet backup error: %v", err)
+			}
+
+			out := w.ExportBase64()
+			if format == KeyFormatMnemonic {
+				words := w.Bip39Mnemonic(&pwd)
+				if words == nil {
+					return errors.New("failed to decrypt the mnemonic of the wallet")
+				}
+				out = strings.Join(words, " ")
+			}
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Anyone with the output controls the reporting identity, keep it offline!")
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), out)
+			return err
+		},
+	}
+	export.Flags().StringVar(&format, "format", KeyFormatMnemonic, fmt.Sprintf("%s, %s or %s", KeyFormatMnemonic, KeyFormatWallet, KeyFormatHex))
+
+	showAddress := &cobra.Command{
+		Use:   "show-address",
+		Short: "Print the address and the signing public key of the private key.",
+		Long: `Print the address owning the DA namespace and the public key to add to verification.signers of the peers.
+They're read without the passphrase, except for the private files of the previous versions.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if data, err := os.ReadFile(a.PrivatePath); err == nil && !keystore.IsPlaintext(data) {
+				ks, err := keystore.Parse(data)
+				if err != nil {
+					return exitErrorf(ExitConfig, "%v", err)
+				}
+				if ks.Address != "" && ks.PublicKey != "" {
+					return printIdentity(cmd.OutOrStdout(), ks.Address, ks.PublicKey)
+				}
+			}
+			key, err := configs.ReadPrivate(a.PrivatePath, a.passphrase)
+			if err != nil {
+				return exitErrorf(ExitConfig, "failed to read private key: %v", err)
+			}
+			return printKeyIdentity(cmd.OutOrStdout(), key)
+		},
+	}
+
+	rotate := &cobra.Command{
+		Use:   "rotate",
+		Short: "Move the private file aside and generate a new private key.",
+		Long: `Move the private file aside, so it could still be restored, then generate a new private key like "key generate".
+The DA namespace is owned by the old key, so create a new one by "namespace create", and the peers verifying the
+signatures must add the new public key to verification.signers. A running light indexer keeps the old key until it's
+restarted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := a.passphrase(true); err != nil {
+				return exitErrorf(ExitConfig, "%v", err)
+			}
+			if err := a.movePrivate(cmd.ErrOrStderr()); err != nil {
+				return err
+			}
+			if err := a.generateKey(cmd.OutOrStdout(), cmd.ErrOrStderr()); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), `The DA namespace is owned by the old key, run "namespace create" for a new one,
+and ask the peers to add the new public key to verification.signers.`)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(generate, importMnemonic, export, showAddress, rotate)
+	return cmd
+}
+
+// generateKey saves a private key from a new wallet, and prints the mnemonic of the wallet.
+func (a *App) generateKey(out, errOut io.Writer) error {
+	pass, err := a.passphrase(true)
+	if err != nil {
+		return exitErrorf(ExitConfig, "%v", err)
+	}
+	pwd := string(pass)
+	w := wallet.NewWallet(&pwd)
+	key, backup, err := configs.WalletPrivate(w, pwd)
+	if err != nil {
+		return err
+	}
+	if err := configs.WritePrivate(a.PrivatePath, key, backup, a.passphrase); err != nil {
+		return fmt.Errorf("write private key to file error: %v", err)
+	}
+
+	_, _ = fmt.Fprintln(errOut, "Write down the mnemonic and keep it offline, it restores the private key by \"key import-mnemonic\":")
+	if _, err := fmt.Fprintln(out, strings.Join(w.Bip39Mnemonic(&pwd), " ")); err != nil {
+		return err
+	}
+	return printKeyIdentity(out, key)
+}
+
+// checkNoPrivate fails if the private file exists, so it's never overwritten by accident.
+func (a *App) checkNoPrivate() error {
+	if _, err := os.Stat(a.PrivatePath); err == nil {
+		return exitErrorf(ExitConfig, "private file %q exists, use \"key rotate\" to replace it", a.PrivatePath)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// movePrivate renames the private file with the timestamp, so the old key could still be restored.
+func (a *App) movePrivate(errOut io.Writer) error {
+	moved := fmt.Sprintf("%s.%d.bak", a.PrivatePath, time.Now().Unix())
+	if err := os.Rename(a.PrivatePath, moved); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("move private file error: path=%s, err=%v", a.PrivatePath, err)
+	}
+	_, _ = fmt.Fprintf(errOut, "Private file moved to %q.\n", moved)
+	return nil
+}
+
+// readSecret reads the first non-empty line of the mnemonic or the wallet backup.
+func readSecret(in io.Reader, errOut io.Writer, fromWallet bool) (string, error) {
+	what := "mnemonic"
+	if fromWallet {
+		what = "wallet backup"
+	}
+	if in == os.Stdin && term.IsTerminal(int(os.Stdin.Fd())) {
+		_, _ = fmt.Fprintf(errOut, "Please enter the %s: ", what)
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("%s required but the input is closed: %v", what, scanner.Err())
+}
+
+func printKeyIdentity(out io.Writer, key string) error {
+	publicKey, err := configs.PrivatePublicKey(key)
+	if err != nil {
+		return err
+	}
+	return printIdentity(out, utils.PrivateStrToBtcAddress(key), publicKey)
+}
+
+func printIdentity(out io.Writer, address, publicKey string) error {
+	_, err := fmt.Fprintf(out, "Address: %s\nPublic key: %s\n", address, publicKey)
+	return err
+}
@@ -0,0 +1,112 @@
+package apps
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/reports"
+)
+
+// DefaultNamespaceTimeout is the timeout of creating a DA namespace, including waiting for it in a block.
+const DefaultNamespaceTimeout = 2 * time.Minute
+
+func (a *App) namespaceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "namespace",
+		Short: "Manage the DA namespace to report checkpoints to.",
+	}
+
+	var save bool
+	create := &cobra.Command{
+		Use:   "create [NAME]",
+		Short: "Create the DA namespace, or reuse the one of the name owned by the private key.",
+		Long: `Create the DA namespace of the name, report.namespaceName by default, owned by the private key. If the key
+already owns a namespace of the name it's reused, so the command is safe to run again. The namespace ID is printed and
+saved as report.namespaceID of the configuration file.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := a.readConfig(cmd)
+			if err != nil {
+				return err
+			}
+			name := c.Report.NamespaceName
+			if len(args) > 0 {
+				name = args[0]
+			}
+			if strings.TrimSpace(name) == "" {
+				return exitErrorf(ExitConfig, "namespace name required: pass NAME or set report.namespaceName")
+			}
+			if c.Report.Network == "" || c.Report.GasCoupon == "" {
+				return exitErrorf(ExitConfig, "report.network and report.gasCoupon are required")
+			}
+			if err := c.Report.LoadPrivate(a.PrivatePath, a.passphrase); err != nil {
+				return exitErrorf(ExitConfig, "failed to read private key: %v", err)
+			}
+			nid, err := createNamespace(cmd.Context(), &c.Report, name)
+			if err != nil {
+				return err
+			}
+			if save {
+				if err := a.saveNamespaceID(nid); err != nil {
+					return err
+				}
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), nid)
+			return err
+		},
+	}
+	create.Flags().BoolVar(&save, "save", true, "save the namespace ID to the configuration file")
+	cmd.AddCommand(create)
+	return cmd
+}
+
+// createNamespace returns the ID of the namespace of the name, created if the key doesn't own one yet.
+func createNamespace(ctx context.Context, r *configs.Report, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultNamespaceTimeout)
+	defer cancel()
+	nid, created, err := reports.CreateNamespace(ctx, r.Network, r.PrivateKey, r.GasCoupon, name)
+	if err != nil {
+		return "", exitErrorf(ExitUnavailable, "failed to create namespace: %v", err)
+	}
+	if created {
+		logs.Info.With("name", name).Printf("Namespace created successfully: %s", nid)
+	} else {
+		logs.Info.With("name", name).Printf("Namespace of the name found, reusing it: %s", nid)
+	}
+	return nid, nil
+}
+
+// saveNamespaceID saves the namespace ID to the configuration file.
+func (a *App) saveNamespaceID(nid string) error {
+	if a.ConfigPath == "" {
+		logs.Warn.Printf("No configuration file to save the namespace ID, set it by LIGHT_REPORT_NAMESPACE_ID: %s", nid)
+		return nil
+	}
+	if err := configs.WriteField(a.ConfigPath, "report.namespaceID", nid); err != nil {
+		return exitErrorf(ExitConfig, "failed to save namespace ID to configuration file: %v", err)
+	}
+	return nil
+}
+
+// promptNamespaceName asks for the namespace name until a non-empty one is entered.
+func promptNamespaceName(in io.Reader, out io.Writer) (string, error) {
+	scanner := bufio.NewScanner(in)
+	for {
+		_, _ = fmt.Fprint(out, "Please enter your desired namespace name:")
+		if !scanner.Scan() {
+			return "", fmt.Errorf("namespace name required but the input is closed: %v", scanner.Err())
+		}
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			return name, nil
+		}
+		_, _ = fmt.Fprint(out, "Namespace name required!")
+	}
+}
@@ -0,0 +1,68 @@
+package apps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// EnvPassphrase is the environment variable of the passphrase of the private key file.
+const EnvPassphrase = "LIGHT_PRIVATE_PASSPHRASE"
+
+// passphrase returns the passphrase of the private key file, from the environment variable, the passphrase file or the
+// terminal in order. It's cached once read, so it's asked at most once.
+func (a *App) passphrase(confirm bool) ([]byte, error) {
+	if a.pass != nil {
+		return a.pass, nil
+	}
+
+	var pass []byte
+	switch env, ok := os.LookupEnv(EnvPassphrase); {
+	case ok:
+		pass = []byte(env)
+	case a.PassphrasePath != "":
+		data, err := os.ReadFile(a.PassphrasePath)
+		if err != nil {
+			return nil, fmt.Errorf("read passphrase file error: %v", err)
+		}
+		pass = bytes.TrimRight(data, "\r\n")
+	case term.IsTerminal(int(os.Stdin.Fd())):
+		var err error
+		if pass, err = promptPassphrase(confirm); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("passphrase of the private key required: set %s or --passphrase-file", EnvPassphrase)
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("empty passphrase of the private key")
+	}
+	a.pass = pass
+	return pass, nil
+}
+
+func promptPassphrase(confirm bool) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	_, _ = fmt.Fprint(os.Stderr, "Passphrase of the private key: ")
+	pass, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase error: %v", err)
+	}
+	if !confirm {
+		return pass, nil
+	}
+	_, _ = fmt.Fprint(os.Stderr, "Repeat the passphrase: ")
+	repeated, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase error: %v", err)
+	}
+	if !bytes.Equal(pass, repeated) {
+		return nil, errors.New("passphrases don't match")
+	}
+	return pass, nil
+}
@@ -0,0 +1,132 @@
+package apps
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+// DefaultReloadInterval is the interval of checking the config file and the deny list for changes.
+const DefaultReloadInterval = 5 * time.Second
+
+// watchReload reloads the config file and the deny list when either of them changes or on SIGHUP, until the context
+// is done.
+func (a *App) watchReload(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(hup, reloadSignals...)
+		defer signal.Stop(hup)
+	}
+
+	last := a.stamps()
+	next := a.nextExpiry(configs.Applied().Verification.DenyExpiry.Duration)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logs.Info.Println("Signal received, reloading the configuration")
+		case <-time.After(DefaultReloadInterval):
+			if a.stamps() != last {
+				logs.Info.Println("Configuration changed, reloading")
+			} else if !next.IsZero() && !time.Now().Before(next) {
+				logs.Info.Println("Deny list entry expired, reloading")
+			} else {
+				continue
+			}
+		}
+		last = a.stamps()
+		var err error
+		if next, err = a.reload(); err != nil {
+			logs.Error.Printf("Failed to reload, keeping the current configuration: %v", err)
+		}
+	}
+}
+
+// nextExpiry returns when the next deny list entry expires, zero means none.
+func (a *App) nextExpiry(expiry time.Duration) time.Time {
+	items, err := configs.ReadDenyList(a.DenyListPath)
+	if err != nil {
+		logs.Error.Printf("Failed to read deny list: %v", err)
+		return time.Time{}
+	}
+	return configs.NextExpiry(items, expiry, time.Now())
+}
+
+// fileStamp identifies a version of a file, the zero value means the file doesn't exist.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(path string) fileStamp {
+	if path == "" {
+		return fileStamp{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func (a *App) stamps() [2]fileStamp {
+	return [2]fileStamp{stampOf(a.ConfigPath), stampOf(a.DenyListPath)}
+}
+
+// reload validates the config file with the deny list, then swaps the committee indexers and the minimal checkpoint
+// of the state, and applies the config for configs.Applied. The verified checkpoints are kept, and the other fields
+// only take effect after a restart. It returns when the next deny list entry expires.
+func (a *App) reload() (time.Time, error) {
+	c, err := configs.Load(a.ConfigPath, a.DenyListPath, a.overrides...)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	prev := configs.Applied()
+	report := prev.Report
+	report.PrivateKey = ""
+	for _, f := range []struct {
+		field   string
+		changed bool
+	}{
+		{"listenAddr", c.ListenAddr != prev.ListenAddr},
+		{"admin", c.Admin.ListenAddr != prev.Admin.ListenAddr || c.Admin.Token != prev.Admin.Token ||
+			!slices.Equal(c.Admin.WebhookAllowList, prev.Admin.WebhookAllowList)},
+		{"verification.bitcoinRPC", c.Verification.BitcoinRPC != prev.Verification.BitcoinRPC},
+		{"verification.metaProtocol", c.Verification.MetaProtocol != prev.Verification.MetaProtocol},
+		{"verification.historySize", c.Verification.HistorySize != prev.Verification.HistorySize},
+		{"report", c.Report != report},
+		{"fraud", c.Fraud.NamespaceID != prev.Fraud.NamespaceID || c.Fraud.Webhook != prev.Fraud.Webhook},
+		{"sinks", !slices.Equal(c.Sinks.S3, prev.Sinks.S3) || !slices.Equal(c.Sinks.Webhook, prev.Sinks.Webhook) ||
+			!slices.Equal(c.Sinks.File, prev.Sinks.File)},
+		{"log", c.Log != prev.Log},
+		{"tracing", c.Tracing != prev.Tracing},
+		{"health", c.Health.MaxSyncInterval != prev.Health.MaxSyncInterval ||
+			!equalPtr(c.Health.MaxLagBlocks, prev.Health.MaxLagBlocks)},
+	} {
+		if f.changed {
+			logs.Warn.Printf("Change of %s takes effect after a restart", f.field)
+		}
+	}
+
+	if err := states.S.SetProviders(a.providers(c), c.Verification.MinimalCheckpoint); err != nil {
+		return time.Time{}, err
+	}
+	configs.Apply(c)
+	return a.nextExpiry(c.Verification.DenyExpiry.Duration), nil
+}
+
+// equalPtr reports whether both are nil or point to equal values.
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,8 @@
+//go:build js || windows
+
+package apps
+
+import "os"
+
+// reloadSignals are the signals triggering a reload, there is no SIGHUP on this platform.
+var reloadSignals []os.Signal
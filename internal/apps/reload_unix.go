@@ -0,0 +1,11 @@
+//go:build !js && !windows
+
+package apps
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals are the signals triggering a reload.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
@@ -7,12 +7,17 @@ import (
 	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
 )
 
-type DA struct{}
+type DA struct {
+	Config *configs.SourceDA
+}
 
 func NewProviderDA(sourceDA *configs.SourceDA, metaProtocol string) *DA {
-	_ = sourceDA
 	_ = metaProtocol
-	return new(DA)
+	return &DA{Config: sourceDA}
+}
+
+func (p *DA) String() string {
+	return "da:" + p.Config.Name
 }
 
 func (p *DA) Get(context.Context, uint, string) (*configs.CheckpointExport, error) {
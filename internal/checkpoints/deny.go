@@ -0,0 +1,135 @@
+package checkpoints
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+)
+
+// Verdict of re-verifying a deny list entry.
+type Verdict string
+
+const (
+	// VerdictConfirmed means the fraud checkpoint is still served and the other providers agree on the correct one.
+	VerdictConfirmed Verdict = "confirmed"
+
+	// VerdictRefuted means the evidence doesn't hold, e.g. the block is reorganized or the other providers agree on the
+	// fraud commitment.
+	VerdictRefuted Verdict = "refuted"
+
+	// VerdictInconclusive means the evidence could neither be confirmed nor refuted, e.g. the providers are unreachable.
+	VerdictInconclusive Verdict = "inconclusive"
+
+	// VerdictNoEvidence means the entry was added by hand without evidence.
+	VerdictNoEvidence Verdict = "no-evidence"
+)
+
+// DenialCheck is one check of re-verifying a deny list entry.
+type DenialCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// DenialReport is the result of re-verifying a deny list entry.
+type DenialReport struct {
+	Name    string            `json:"name"`
+	Height  uint              `json:"height,omitempty"`
+	Verdict Verdict           `json:"verdict"`
+	Checks  []*DenialCheck    `json:"checks"`
+	Entry   *configs.DenyList `json:"entry"`
+}
+
+func (r *DenialReport) check(name string, ok bool, format string, a ...any) {
+	r.Checks = append(r.Checks, &DenialCheck{Name: name, OK: ok, Message: fmt.Sprintf(format, a...)})
+}
+
+// VerifyDenial re-verifies the evidence of the entry: the block hash against Bitcoin, the checkpoint of the denied
+// committee indexer and the checkpoints of the other providers at the height. The fetches are retried until the context
+// is done, so it should have a deadline.
+func VerifyDenial(
+	ctx context.Context,
+	btc *btcutl.Client,
+	d *configs.DenyList,
+	providers []CheckpointProvider,
+	metaProtocol string,
+) *DenialReport {
+	r := &DenialReport{Name: d.Name(), Entry: d}
+	e := d.Evidence
+	if e == nil {
+		r.Verdict = VerdictNoEvidence
+		return r
+	}
+	r.Height = e.Height
+
+	hash, err := btc.GetBlockHash(ctx, e.Height)
+	if err != nil {
+		r.check("bitcoin", false, "get block hash error: %v", err)
+		r.Verdict = VerdictInconclusive
+		return r
+	}
+	if hash != e.Hash {
+		r.check("bitcoin", false, "block hash mismatch, the block may be reorganized: expected=%s, actual=%s", e.Hash, hash)
+		r.Verdict = VerdictRefuted
+		return r
+	}
+	r.check("bitcoin", true, "block hash matches")
+
+	var denied CheckpointProvider
+	switch {
+	case d.SourceS3 != nil:
+		denied = NewProviderS3(d.SourceS3, metaProtocol)
+	case d.SourceDA != nil:
+		denied = NewProviderDA(d.SourceDA, metaProtocol)
+	}
+	reproduced := false
+	if ck, err := getCheckpoint(ctx, denied, e.Height, e.Hash); err != nil {
+		r.check("denied", false, "get checkpoint error: %v", err)
+	} else if c := ck.Checkpoint.Commitment; c == e.FraudCommitment {
+		reproduced = true
+		r.check("denied", true, "fraud commitment still served")
+	} else {
+		r.check("denied", false, "fraud commitment no longer served: commitment=%s", c)
+	}
+
+	var others []CheckpointProvider
+	for _, p := range providers {
+		if !sameSource(p, d) {
+			others = append(others, p)
+		}
+	}
+	cks, _ := GetCheckpoints(ctx, others, e.Height, e.Hash)
+	correct, fraud := 0, 0
+	for _, ck := range cks {
+		switch ck.Checkpoint.Commitment {
+		case e.CorrectCommitment:
+			correct++
+		case e.FraudCommitment:
+			fraud++
+		}
+	}
+	r.check("others", correct > fraud, "correct=%d, fraud=%d, answered=%d, total=%d", correct, fraud, len(cks), len(others))
+
+	switch {
+	case fraud > correct:
+		r.Verdict = VerdictRefuted
+	case reproduced && correct > 0:
+		r.Verdict = VerdictConfirmed
+	default:
+		r.Verdict = VerdictInconclusive
+	}
+	return r
+}
+
+// sameSource reports whether the provider is the committee indexer of the entry.
+func sameSource(p CheckpointProvider, d *configs.DenyList) bool {
+	switch p := p.(type) {
+	case *S3:
+		return d.SourceS3 != nil && p.Config.Equal(d.SourceS3)
+	case *DA:
+		return d.SourceDA != nil && p.Config.Equal(d.SourceDA)
+	}
+	return false
+}
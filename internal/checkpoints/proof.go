@@ -0,0 +1,242 @@
+package checkpoints
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/RiemaLabs/modular-indexer-committee/apis"
+	"github.com/RiemaLabs/modular-indexer-committee/ord/getter"
+	"github.com/ethereum/go-verkle"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/ordi"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
+)
+
+// ProofError is an error of the state proof served by a committee indexer, which is evidence of fraud.
+type ProofError struct {
+	Failure configs.Failure
+
+	// Recomputed is the recomputed commitment, empty if the state proof couldn't be applied.
+	Recomputed string
+	Err        error
+}
+
+func (e *ProofError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Failure, e.Err)
+}
+
+func (e *ProofError) Unwrap() error {
+	return e.Err
+}
+
+// OrdTransfers decodes the Ordinals transfers of the state proof.
+func OrdTransfers(proof *apis.Brc20VerifiableLatestStateProofResponse) ([]getter.OrdTransfer, error) {
+	if proof.Result == nil {
+		return nil, nil
+	}
+	var ret []getter.OrdTransfer
+	for _, t := range proof.Result.OrdTransfers {
+		content, err := base64.StdEncoding.DecodeString(t.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Ordinals transfer content: id=%d, err=%v", t.ID, err)
+		}
+		ret = append(ret, getter.OrdTransfer{
+			ID:            t.ID,
+			InscriptionID: t.InscriptionID,
+			OldSatpoint:   t.OldSatpoint,
+			NewSatpoint:   t.NewSatpoint,
+			NewPkscript:   t.NewPkscript,
+			NewWallet:     t.NewWallet,
+			SentAsFee:     t.SentAsFee,
+			Content:       content,
+			ContentType:   t.ContentType,
+		})
+	}
+	return ret, nil
+}
+
+// Recompute applies the state proof to the previous commitment and returns the commitment at the height. Errors of the
+// state proof are *ProofError.
+func Recompute(
+	ctx context.Context,
+	prevCommitment string,
+	height uint,
+	proof *apis.Brc20VerifiableLatestStateProofResponse,
+) (commitment string, err error) {
+	prePointBytes, err := base64.StdEncoding.DecodeString(prevCommitment)
+	if err != nil {
+		return "", fmt.Errorf("invalid previous commitment: commitment=%s, err=%v", prevCommitment, err)
+	}
+	prePoint := new(verkle.Point)
+	if err := prePoint.SetBytes(prePointBytes); err != nil {
+		return "", fmt.Errorf("invalid previous commitment: commitment=%s, err=%v", prevCommitment, err)
+	}
+	if proof.Error != nil {
+		return "", &ProofError{Failure: configs.FailureInvalidProof, Err: errors.New(*proof.Error)}
+	}
+	if proof.Result == nil {
+		return "", &ProofError{Failure: configs.FailureInvalidProof, Err: errors.New("empty state proof")}
+	}
+
+	node, err := generatePostRoot(ctx, prePoint, height, proof)
+	if err != nil {
+		return "", &ProofError{Failure: configs.FailureInvalidProof, Err: err}
+	}
+	if node == nil {
+		return "", &ProofError{Failure: configs.FailureInvalidProof, Err: errors.New("empty post root")}
+	}
+	postBytes := node.Commit().Bytes()
+	return base64.StdEncoding.EncodeToString(postBytes[:]), nil
+}
+
+func generatePostRoot(
+	ctx context.Context,
+	prePoint *verkle.Point,
+	height uint,
+	proof *apis.Brc20VerifiableLatestStateProofResponse,
+) (node verkle.VerkleNode, err error) {
+	_, span := tracing.Start(ctx, "apis.GeneratePostRoot", attribute.Int64("height", int64(height)))
+	defer func() { tracing.End(span, err) }()
+	return apis.GeneratePostRoot(prePoint, height, proof)
+}
+
+// VerifyStateProof verifies the Ordinals transfers of the state proof against Bitcoin, then checks the state proof
+// applied to the previous commitment produces the commitment. It returns the number of the transfers, errors of the
+// state proof are *ProofError, while the others, such as those of the Bitcoin RPC, are no evidence of fraud.
+func VerifyStateProof(
+	ctx context.Context,
+	btc *btcutl.Client,
+	prevCommitment string,
+	height uint,
+	commitment string,
+	proof *apis.Brc20VerifiableLatestStateProofResponse,
+) (int, error) {
+	transfers, err := OrdTransfers(proof)
+	if err != nil {
+		return 0, &ProofError{Failure: configs.FailureInvalidProof, Err: err}
+	}
+	if err := ordi.VerifyOrdTransfer(ctx, btc, transfers, height); errors.Is(err, ordi.ErrMismatch) {
+		return 0, &ProofError{Failure: configs.FailureInvalidTransfers, Err: err}
+	} else if err != nil {
+		return 0, fmt.Errorf("verify Ordinals transfers error: height=%d, err=%v", height, err)
+	}
+	recomputed, err := Recompute(ctx, prevCommitment, height, proof)
+	if err != nil {
+		return 0, err
+	}
+	if recomputed != commitment {
+		return 0, &ProofError{
+			Failure:    configs.FailureCommitmentMismatch,
+			Recomputed: recomputed,
+			Err:        fmt.Errorf("inconsistent commitments: expected=%s, actual=%s", commitment, recomputed),
+		}
+	}
+	return len(transfers), nil
+}
+
+// NewFraudProof bundles the state proof failing the verification of the commitment.
+func NewFraudProof(
+	height uint,
+	hash string,
+	prevCommitment string,
+	commitment string,
+	name string,
+	url string,
+	proof *apis.Brc20VerifiableLatestStateProofResponse,
+	err *ProofError,
+) *configs.FraudProof {
+	return &configs.FraudProof{
+		Height:               height,
+		Hash:                 hash,
+		PrevCommitment:       prevCommitment,
+		Commitment:           commitment,
+		RecomputedCommitment: err.Recomputed,
+		Failure:              err.Failure,
+		Message:              err.Err.Error(),
+		Name:                 name,
+		URL:                  url,
+		StateProof:           proof,
+	}
+}
+
+// VerifyFraudProof re-checks the fraud proof offline by applying the state proof to the previous commitment. The
+// previous commitment is taken as is, it should be checked against a trusted checkpoint. With a non-nil Bitcoin client,
+// the block hash and the Ordinals transfers are verified as well, which is required to confirm invalid transfers. The
+// bundle doesn't prove the accused indexer served the state proof, so the verdict is only as trustworthy as the origin
+// of the bundle. A state proof that couldn't be applied at all is inconclusive, and so are the transfers that couldn't be
+// checked against Bitcoin, e.g. on an RPC failure.
+func VerifyFraudProof(ctx context.Context, btc *btcutl.Client, p *configs.FraudProof) *DenialReport {
+	r := &DenialReport{Name: p.Name, Height: p.Height}
+	if p.StateProof == nil {
+		r.check("proof", false, "missing state proof")
+		r.Verdict = VerdictRefuted
+		return r
+	}
+
+	if btc != nil {
+		hash, err := btc.GetBlockHash(ctx, p.Height)
+		if err != nil {
+			r.check("bitcoin", false, "get block hash error: %v", err)
+			r.Verdict = VerdictInconclusive
+			return r
+		}
+		if hash != p.Hash {
+			r.check("bitcoin", false, "block hash mismatch, the block may be reorganized: expected=%s, actual=%s", p.Hash, hash)
+			r.Verdict = VerdictRefuted
+			return r
+		}
+		r.check("bitcoin", true, "block hash matches")
+	}
+
+	recomputed, err := Recompute(ctx, p.PrevCommitment, p.Height, p.StateProof)
+	var proofErr *ProofError
+	switch {
+	case errors.As(err, &proofErr):
+		// Anyone could bundle a broken state proof, nothing ties it to the accused indexer.
+		r.check("proof", false, "state proof couldn't be applied to the previous commitment: %v", proofErr.Err)
+		r.Verdict = VerdictInconclusive
+		return r
+	case err != nil:
+		r.check("proof", false, "%v", err)
+		r.Verdict = VerdictRefuted
+		return r
+	case recomputed != p.Commitment:
+		r.check("proof", true, "recomputed commitment differs: claimed=%s, recomputed=%s", p.Commitment, recomputed)
+		r.Verdict = VerdictConfirmed
+		return r
+	}
+	r.check("proof", false, "recomputed commitment matches the claimed one")
+
+	if btc == nil {
+		if p.Failure == configs.FailureInvalidTransfers {
+			r.check("transfers", false, "Bitcoin RPC is required to verify the Ordinals transfers")
+			r.Verdict = VerdictInconclusive
+		} else {
+			r.Verdict = VerdictRefuted
+		}
+		return r
+	}
+	transfers, err := OrdTransfers(p.StateProof)
+	if err != nil {
+		r.check("transfers", true, "%v", err)
+		r.Verdict = VerdictConfirmed
+		return r
+	}
+	if err := ordi.VerifyOrdTransfer(ctx, btc, transfers, p.Height); errors.Is(err, ordi.ErrMismatch) {
+		r.check("transfers", true, "%v", err)
+		r.Verdict = VerdictConfirmed
+		return r
+	} else if err != nil {
+		r.check("transfers", false, "Ordinals transfers couldn't be verified: %v", err)
+		r.Verdict = VerdictInconclusive
+		return r
+	}
+	r.check("transfers", false, "Ordinals transfers match Bitcoin")
+	r.Verdict = VerdictRefuted
+	return r
+}
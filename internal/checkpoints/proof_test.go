@@ -0,0 +1,166 @@
+package checkpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/RiemaLabs/modular-indexer-committee/apis"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-verkle"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+)
+
+func TestVerifyFraudProof(t *testing.T) {
+	var point verkle.Point
+	if err := point.SetBytes(make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	b := point.Bytes()
+	prev := base64.StdEncoding.EncodeToString(b[:])
+	// Applying a state proof without transfers keeps the commitment.
+	empty := &apis.Brc20VerifiableLatestStateProofResponse{Result: &apis.Brc20VerifiableLatestStateProofResult{}}
+	errMsg := "not ready"
+
+	for _, c := range []struct {
+		name    string
+		proof   *configs.FraudProof
+		verdict Verdict
+	}{
+		{
+			name:    "mismatch",
+			proof:   &configs.FraudProof{PrevCommitment: prev, Commitment: "fraud", StateProof: empty},
+			verdict: VerdictConfirmed,
+		},
+		{
+			name:    "match",
+			proof:   &configs.FraudProof{PrevCommitment: prev, Commitment: prev, StateProof: empty, Failure: configs.FailureCommitmentMismatch},
+			verdict: VerdictRefuted,
+		},
+		{
+			name:    "transfers without Bitcoin",
+			proof:   &configs.FraudProof{PrevCommitment: prev, Commitment: prev, StateProof: empty, Failure: configs.FailureInvalidTransfers},
+			verdict: VerdictInconclusive,
+		},
+		{
+			name: "invalid proof",
+			proof: &configs.FraudProof{
+				PrevCommitment: prev,
+				Commitment:     prev,
+				StateProof:     &apis.Brc20VerifiableLatestStateProofResponse{Error: &errMsg},
+			},
+			// Could be forged by anyone.
+			verdict: VerdictInconclusive,
+		},
+		{
+			name:    "invalid previous commitment",
+			proof:   &configs.FraudProof{PrevCommitment: "!", Commitment: prev, StateProof: empty},
+			verdict: VerdictRefuted,
+		},
+		{
+			name:    "missing state proof",
+			proof:   &configs.FraudProof{PrevCommitment: prev, Commitment: "fraud"},
+			verdict: VerdictRefuted,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if r := VerifyFraudProof(context.Background(), nil, c.proof); r.Verdict != c.verdict {
+				t.Fatalf("unexpected verdict: expected=%s, actual=%s, checks=%v", c.verdict, r.Verdict, r.Checks[0])
+			}
+		})
+	}
+}
+
+// fakeBitcoin serves a block of one transaction without inscriptions, or fails the calls of the block.
+type fakeBitcoin struct {
+	tx   *wire.MsgTx
+	fail bool
+}
+
+func (f *fakeBitcoin) Call(_ context.Context, method string, _, out any) error {
+	var result any
+	switch method {
+	case "getblockhash":
+		result = "hash"
+	case "getblock":
+		if f.fail {
+			return errors.New("connection refused")
+		}
+		var buf bytes.Buffer
+		if err := f.tx.Serialize(&buf); err != nil {
+			return err
+		}
+		result = &btcjson.GetBlockVerboseTxResult{Tx: []btcjson.TxRawResult{{Txid: f.tx.TxHash().String(), Hex: hex.EncodeToString(buf.Bytes())}}}
+	case "getrawtransaction":
+		result = &btcjson.TxRawResult{Vout: []btcjson.Vout{{Value: 1}}}
+	default:
+		return fmt.Errorf("unexpected method: %s", method)
+	}
+	data, err := json.Marshal(map[string]any{"result": result})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func TestVerifyFraudProof_Bitcoin(t *testing.T) {
+	var point verkle.Point
+	if err := point.SetBytes(make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	b := point.Bytes()
+	prev := base64.StdEncoding.EncodeToString(b[:])
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(100_000_000, []byte{txscript.OP_TRUE}))
+	// The transaction moves no inscription, so there is no transfer to match.
+	transfers := &apis.Brc20VerifiableLatestStateProofResponse{Result: &apis.Brc20VerifiableLatestStateProofResult{
+		OrdTransfers: []apis.OrdTransferJSON{{InscriptionID: tx.TxHash().String() + "i0", NewSatpoint: tx.TxHash().String() + ":0:0"}},
+	}}
+	empty := &apis.Brc20VerifiableLatestStateProofResponse{Result: &apis.Brc20VerifiableLatestStateProofResult{}}
+
+	for _, c := range []struct {
+		name    string
+		btc     *fakeBitcoin
+		proof   *apis.Brc20VerifiableLatestStateProofResponse
+		verdict Verdict
+	}{
+		{"mismatch", &fakeBitcoin{tx: tx}, transfers, VerdictConfirmed},
+		{"RPC failure", &fakeBitcoin{tx: tx, fail: true}, transfers, VerdictInconclusive},
+		{"no transfers", &fakeBitcoin{tx: tx}, empty, VerdictInconclusive},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			p := &configs.FraudProof{
+				Hash:           "hash",
+				PrevCommitment: prev,
+				Commitment:     prev,
+				StateProof:     c.proof,
+				Failure:        configs.FailureInvalidTransfers,
+			}
+			if r := VerifyFraudProof(context.Background(), btcutl.NewWithRPC(c.btc), p); r.Verdict != c.verdict {
+				t.Fatalf("unexpected verdict: expected=%s, actual=%s, checks=%v", c.verdict, r.Verdict, r.Checks)
+			}
+		})
+	}
+
+	var proofErr *ProofError
+	_, err := VerifyStateProof(context.Background(), btcutl.NewWithRPC(&fakeBitcoin{tx: tx, fail: true}), prev, 0, prev, transfers)
+	if err == nil || errors.As(err, &proofErr) {
+		t.Fatal("expected the RPC failure not to be evidence of fraud", err)
+	}
+	_, err = VerifyStateProof(context.Background(), btcutl.NewWithRPC(&fakeBitcoin{tx: tx}), prev, 0, prev, transfers)
+	if !errors.As(err, &proofErr) || proofErr.Failure != configs.FailureInvalidTransfers {
+		t.Fatal("expected invalid transfers", err)
+	}
+}
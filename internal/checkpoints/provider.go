@@ -3,20 +3,47 @@ package checkpoints
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
 	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
 )
 
 const DefaultRetries = 3
 
+const (
+	// DefaultRetryBackoff is the interval before fetching the checkpoint of a provider again, doubled on every failure up
+	// to DefaultMaxRetryBackoff.
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultMaxRetryBackoff = 10 * time.Second
+)
+
+// ErrSignature is wrapped by the errors of the checkpoints with invalid or untrusted signatures. Fetching them again
+// gets the same checkpoints, so the provider is left out of the height instead.
+var ErrSignature = errors.New("verify checkpoint signature error")
+
 type CheckpointProvider interface {
 	Get(ctx context.Context, height uint, hash string) (*configs.CheckpointExport, error)
 }
 
+// ProviderName returns a human-readable identity of the provider.
+func ProviderName(p CheckpointProvider) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// GetCheckpoints fetches the checkpoints of the providers at the height, retrying the failed ones with backoff until the
+// context is done, which is the error. The providers failing with ErrSignature are left out without an error.
 func GetCheckpoints(ctx context.Context, providers []CheckpointProvider, height uint, hash string) ([]*configs.CheckpointExport, error) {
 	var (
 		wg          sync.WaitGroup
@@ -27,20 +54,31 @@ func GetCheckpoints(ctx context.Context, providers []CheckpointProvider, height
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for {
+			for failures := 0; ; failures++ {
 				select {
 				case <-ctx.Done():
 					errs <- ctx.Err()
 					return
 				default:
-					ck, err := p.Get(ctx, height, hash)
-					if err != nil {
-						logs.Error.Printf("Get checkpoint error: height=%d, hash=%s, err=%v", height, hash, err)
-						continue
-					}
+				}
+				start := time.Now()
+				ck, err := getCheckpoint(ctx, p, height, hash)
+				metrics.Since(metrics.ProviderFetchDuration.WithLabelValues(ProviderName(p), metrics.Result(err)), start)
+				if err == nil {
 					checkpoints <- ck
 					return
 				}
+				metrics.ProviderFetchErrors.WithLabelValues(ProviderName(p)).Inc()
+				errLog := logs.Error.With("provider", ProviderName(p), "height", height, "hash", hash)
+				if errors.Is(err, ErrSignature) {
+					errLog.Printf("Checkpoint rejected, the provider is left out of the height: %v", err)
+					return
+				}
+				errLog.Printf("Get checkpoint error: %v", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(retryBackoff(failures)):
+				}
 			}
 		}()
 	}
@@ -61,7 +99,30 @@ func GetCheckpoints(ctx context.Context, providers []CheckpointProvider, height
 	return ret, errors.Join(retErrs...)
 }
 
-func Deny(path string, correct, fraud *configs.CheckpointExport) {
+// retryBackoff returns the interval before the next fetch after the failures.
+func retryBackoff(failures int) time.Duration {
+	d := DefaultRetryBackoff
+	for i := 0; i < failures && d < DefaultMaxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, DefaultMaxRetryBackoff)
+}
+
+func getCheckpoint(ctx context.Context, p CheckpointProvider, height uint, hash string) (ck *configs.CheckpointExport, err error) {
+	ctx, span := tracing.Start(
+		ctx,
+		"checkpoints.Get",
+		attribute.String("provider", ProviderName(p)),
+		attribute.Int64("height", int64(height)),
+		attribute.String("hash", hash),
+	)
+	defer func() { tracing.End(span, err) }()
+	return p.Get(ctx, height, hash)
+}
+
+// Deny appends the committee indexer of the fraud checkpoint to the deny list and returns the entry, or nil if it failed
+// to append. The proof is nil if the committee indexer didn't serve a state proof.
+func Deny(path string, correct, fraud *configs.CheckpointExport, proof *configs.FraudProof) *configs.DenyList {
 	h, _ := strconv.ParseUint(correct.Checkpoint.Height, 10, 64)
 	b := configs.DenyList{
 		Evidence: &configs.Evidence{
@@ -69,7 +130,10 @@ func Deny(path string, correct, fraud *configs.CheckpointExport) {
 			Hash:              correct.Checkpoint.Hash,
 			CorrectCommitment: correct.Checkpoint.Commitment,
 			FraudCommitment:   fraud.Checkpoint.Commitment,
+			Proof:             proof,
 		},
+		CreatedAt: time.Now().UTC(),
+		Reason:    "commitment failed the verification",
 	}
 	if fraud.SourceDA != nil {
 		b.SourceDA = fraud.SourceDA
@@ -81,7 +145,10 @@ func Deny(path string, correct, fraud *configs.CheckpointExport) {
 
 	if err := configs.AppendDenyList(path, &b); err != nil {
 		logs.Error.Println("Append to deny list error:", err)
+		return nil
 	}
+	metrics.DenyListAdditions.Inc()
+	return &b
 }
 
 func Inconsistent(checkpoints []*configs.CheckpointExport) bool {
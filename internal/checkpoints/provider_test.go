@@ -0,0 +1,66 @@
+package checkpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+)
+
+// testProvider fails the first fetches with the error, then serves the checkpoint.
+type testProvider struct {
+	name     string
+	err      error
+	failures int32
+
+	calls atomic.Int32
+}
+
+func (p *testProvider) String() string { return p.name }
+
+func (p *testProvider) Get(_ context.Context, height uint, hash string) (*configs.CheckpointExport, error) {
+	if p.calls.Add(1) <= p.failures {
+		return nil, p.err
+	}
+	return &configs.CheckpointExport{Checkpoint: &checkpoint.Checkpoint{Height: fmt.Sprint(height), Hash: hash}}, nil
+}
+
+func TestGetCheckpoints(t *testing.T) {
+	rejected := &testProvider{name: "rejected", err: fmt.Errorf("%w: untrusted signer", ErrSignature), failures: 1}
+	flaky := &testProvider{name: "flaky", err: errors.New("connection reset"), failures: 1}
+	ok := &testProvider{name: "ok"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	cks, err := GetCheckpoints(ctx, []CheckpointProvider{rejected, flaky, ok}, 100, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cks) != 2 {
+		t.Fatalf("unexpected checkpoints: expected=2, actual=%d", len(cks))
+	}
+	if n := rejected.calls.Load(); n != 1 {
+		t.Fatalf("rejected checkpoint fetched again: calls=%d", n)
+	}
+	if elapsed := time.Since(start); flaky.calls.Load() != 2 || elapsed < DefaultRetryBackoff {
+		t.Fatalf("unexpected retry: calls=%d, elapsed=%s", flaky.calls.Load(), elapsed)
+	}
+
+	// Without a deadline the failing providers would be retried forever.
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	failing := &testProvider{name: "failing", err: errors.New("connection reset"), failures: 1000}
+	if _, err := GetCheckpoints(ctx, []CheckpointProvider{failing}, 100, "hash"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected deadline exceeded", err)
+	}
+	if n := failing.calls.Load(); n != 1 {
+		t.Fatalf("retried without backoff: calls=%d", n)
+	}
+}
@@ -18,6 +18,9 @@ import (
 type S3 struct {
 	Config       *configs.SourceS3
 	MetaProtocol string
+
+	// Signers trusted to sign the checkpoints, see Signers.Verify.
+	Signers Signers
 }
 
 func NewProviderS3(sourceS3 *configs.SourceS3, metaProtocol string) *S3 {
@@ -27,9 +30,13 @@ func NewProviderS3(sourceS3 *configs.SourceS3, metaProtocol string) *S3 {
 	}
 }
 
+func (p *S3) String() string {
+	return "s3:" + p.Config.Name
+}
+
 func (p *S3) Get(ctx context.Context, height uint, hash string) (*configs.CheckpointExport, error) {
 	var (
-		ck  *checkpoint.Checkpoint
+		ck  *SignedCheckpoint
 		err error
 	)
 	for i := 0; i < DefaultRetries; i++ {
@@ -37,8 +44,8 @@ func (p *S3) Get(ctx context.Context, height uint, hash string) (*configs.Checkp
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			if ck, err = p.doDownload(height, hash); err != nil {
-				logs.Error.Println("Download S3 checkpoint error:", err)
+			if ck, err = p.doDownload(ctx, height, hash); err != nil {
+				logs.Error.With("provider", p.String(), "height", height, "hash", hash).Println("Download S3 checkpoint error:", err)
 				continue
 			}
 		}
@@ -47,11 +54,15 @@ func (p *S3) Get(ctx context.Context, height uint, hash string) (*configs.Checkp
 	if err != nil {
 		return nil, err
 	}
-	return &configs.CheckpointExport{Checkpoint: ck, SourceS3: p.Config}, nil
+	signer, err := p.Signers.Verify(ck)
+	if err != nil {
+		return nil, fmt.Errorf("%w: provider=%s, height=%d, err=%v", ErrSignature, p.String(), height, err)
+	}
+	return &configs.CheckpointExport{Checkpoint: ck.Checkpoint, SourceS3: p.Config, Signer: signer}, nil
 }
 
-func (p *S3) doDownload(height uint, hash string) (*checkpoint.Checkpoint, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+func (p *S3) doDownload(ctx context.Context, height uint, hash string) (*SignedCheckpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 	u := &url.URL{
 		Scheme: "https",
@@ -76,7 +87,7 @@ func (p *S3) doDownload(height uint, hash string) (*checkpoint.Checkpoint, error
 		return nil, fmt.Errorf("read S3 response error: obj=%s, err=%v", obj, err)
 	}
 
-	var c checkpoint.Checkpoint
+	c := SignedCheckpoint{Checkpoint: new(checkpoint.Checkpoint)}
 	if err := json.Unmarshal(bytes, &c); err != nil {
 		type s3Resp struct {
 			Code, Message string
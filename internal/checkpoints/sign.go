@@ -0,0 +1,126 @@
+package checkpoints
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// SignatureTag is the BIP-340 tag of the hash signed for a checkpoint.
+const SignatureTag = "modular-indexer-light/checkpoint"
+
+// SignedCheckpoint is a checkpoint with the signature of the Light Indexer reporting it. The public key and the
+// signature are empty if it's unsigned, such as those of the previous versions.
+type SignedCheckpoint struct {
+	*checkpoint.Checkpoint
+
+	// PublicKey is the hex of the BIP-340 x-only public key, and Signature is the hex of the Schnorr signature.
+	PublicKey string `json:"publicKey,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Signed reports whether the checkpoint carries a signature.
+func (s *SignedCheckpoint) Signed() bool {
+	return s.PublicKey != "" || s.Signature != ""
+}
+
+// digest returns the tagged hash of the checkpoint in JSON, so every field of the checkpoint is signed.
+func digest(ck *checkpoint.Checkpoint) ([]byte, error) {
+	data, err := json.Marshal(ck)
+	if err != nil {
+		return nil, fmt.Errorf("marshal checkpoint error: %v", err)
+	}
+	return chainhash.TaggedHash([]byte(SignatureTag), data)[:], nil
+}
+
+// ParsePrivateKey parses the hex private key of the report.
+func ParsePrivateKey(key string) (*btcec.PrivateKey, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil || len(data) != btcec.PrivKeyBytesLen {
+		return nil, errors.New("invalid private key")
+	}
+	pk, _ := btcec.PrivKeyFromBytes(data)
+	return pk, nil
+}
+
+// PublicKey returns the hex of the x-only public key of the private key, the one published in signed checkpoints.
+func PublicKey(key *btcec.PrivateKey) string {
+	return hex.EncodeToString(schnorr.SerializePubKey(key.PubKey()))
+}
+
+// Sign signs the checkpoint with the private key.
+func Sign(ck *checkpoint.Checkpoint, key *btcec.PrivateKey) (*SignedCheckpoint, error) {
+	h, err := digest(ck)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := schnorr.Sign(key, h)
+	if err != nil {
+		return nil, fmt.Errorf("sign checkpoint error: %v", err)
+	}
+	return &SignedCheckpoint{
+		Checkpoint: ck,
+		PublicKey:  PublicKey(key),
+		Signature:  hex.EncodeToString(sig.Serialize()),
+	}, nil
+}
+
+// Verify verifies the signature of the checkpoint against its public key.
+func (s *SignedCheckpoint) Verify() error {
+	pubData, err := hex.DecodeString(s.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: publicKey=%s, err=%v", s.PublicKey, err)
+	}
+	pub, err := schnorr.ParsePubKey(pubData)
+	if err != nil {
+		return fmt.Errorf("invalid public key: publicKey=%s, err=%v", s.PublicKey, err)
+	}
+	sigData, err := hex.DecodeString(s.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: signature=%s, err=%v", s.Signature, err)
+	}
+	sig, err := schnorr.ParseSignature(sigData)
+	if err != nil {
+		return fmt.Errorf("invalid signature: signature=%s, err=%v", s.Signature, err)
+	}
+	h, err := digest(s.Checkpoint)
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(h, pub) {
+		return fmt.Errorf("signature mismatch: publicKey=%s", s.PublicKey)
+	}
+	return nil
+}
+
+// Signers is the allow-list of the public keys trusted to sign checkpoints, empty to trust any valid signature and the
+// unsigned checkpoints.
+type Signers []string
+
+// Verify returns the public key of the signer of the checkpoint, empty if it's unsigned. A checkpoint with an invalid
+// signature is rejected, and so is one not signed by a trusted key if there is any.
+func (s Signers) Verify(ck *SignedCheckpoint) (string, error) {
+	if ck.Signed() {
+		if err := ck.Verify(); err != nil {
+			return "", err
+		}
+	}
+	if len(s) == 0 {
+		return ck.PublicKey, nil
+	}
+	if !ck.Signed() {
+		return "", errors.New("unsigned checkpoint, a trusted signer is required")
+	}
+	if !slices.ContainsFunc(s, func(k string) bool { return strings.EqualFold(k, ck.PublicKey) }) {
+		return "", fmt.Errorf("untrusted signer: publicKey=%s", ck.PublicKey)
+	}
+	return ck.PublicKey, nil
+}
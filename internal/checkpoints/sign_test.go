@@ -0,0 +1,55 @@
+package checkpoints
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+func TestSigners_Verify(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePrivateKey("0x" + hex.EncodeToString(key.Serialize()))
+	if err != nil || PublicKey(parsed) != PublicKey(key) {
+		t.Fatal("unexpected parsed key", err)
+	}
+
+	ck := &checkpoint.Checkpoint{Name: "light", Height: "100", Hash: "a", Commitment: "c"}
+	signed, err := Sign(ck, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := *signed
+	tampered.Checkpoint = &checkpoint.Checkpoint{Name: "light", Height: "100", Hash: "a", Commitment: "d"}
+	unsigned := &SignedCheckpoint{Checkpoint: ck}
+
+	for _, c := range []struct {
+		name    string
+		signers Signers
+		ck      *SignedCheckpoint
+		signer  string
+		ok      bool
+	}{
+		{"signed", nil, signed, PublicKey(key), true},
+		{"unsigned", nil, unsigned, "", true},
+		{"tampered", nil, &tampered, "", false},
+		{"trusted", Signers{PublicKey(key)}, signed, PublicKey(key), true},
+		{"untrusted", Signers{PublicKey(other)}, signed, "", false},
+		{"unsigned with signers", Signers{PublicKey(key)}, unsigned, "", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			signer, err := c.signers.Verify(c.ck)
+			if (err == nil) != c.ok || signer != c.signer {
+				t.Fatalf("unexpected result: signer=%s, err=%v", signer, err)
+			}
+		})
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"github.com/btcsuite/btcd/wire"
 
 	"github.com/RiemaLabs/modular-indexer-light/internal/clients/jsonrpc"
-	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
 )
 
 const OK = 0
@@ -44,15 +43,21 @@ func New(bitcoinRPC string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Client{cl: cl}, nil
+	return NewWithRPC(cl), nil
 }
 
-func Init(bitcoinRPC string) {
+// NewWithRPC creates the client on an existing JSON-RPC backend.
+func NewWithRPC(cl jsonrpc.Client) *Client {
+	return &Client{cl: cl}
+}
+
+func Init(bitcoinRPC string) error {
 	cl, err := New(bitcoinRPC)
 	if err != nil {
-		logs.Error.Fatalln("Failed to initialize ord client:", err)
+		return err
 	}
 	BTC = cl
+	return nil
 }
 
 func (c *Client) GetLatestBlockHeight(ctx context.Context) (uint, error) {
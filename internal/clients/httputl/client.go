@@ -1,6 +1,7 @@
 package httputl
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -40,3 +41,48 @@ func GetJSON(ctx context.Context, u *url.URL, out any) error {
 
 	return nil
 }
+
+// PostJSON posts the JSON body and decodes the JSON response into out if it's non-nil, non-2xx responses are errors.
+func PostJSON(ctx context.Context, u *url.URL, in, out any) error {
+	return PostJSONWith(ctx, Client, u, in, out)
+}
+
+// PostJSONWith is like PostJSON but posts by the client.
+func PostJSONWith(ctx context.Context, cl *http.Client, u *url.URL, in, out any) error {
+	rawURL := u.String()
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshal error: rawURL=%s, err=%v", rawURL, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid request: rawURL=%s, err=%v", rawURL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if reqID := RequestID(ctx); reqID != "" {
+		req.Header.Set("X-Request-Id", reqID)
+	}
+
+	rsp, err := cl.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP transport error: rawURL=%s, err=%v", rawURL, err)
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	data, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return fmt.Errorf("response body read error: rawURL=%s, err=%v", rawURL, err)
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: rawURL=%s, status=%s, body=%q", rawURL, rsp.Status, string(data))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unmarshal error: rawURL=%s, err=%v", rawURL, err)
+	}
+
+	return nil
+}
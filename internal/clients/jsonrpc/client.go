@@ -8,8 +8,13 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/RiemaLabs/modular-indexer-light/internal/clients/httputl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
 )
 
 const DefaultVersion = "2.0"
@@ -61,7 +66,14 @@ func New(rawURL string) (Client, error) {
 	return &client{nodeURL: rawURL, cl: httputl.Client}, nil
 }
 
-func (c *client) Call(ctx context.Context, method string, params, out any) error {
+func (c *client) Call(ctx context.Context, method string, params, out any) (err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "jsonrpc.Call", attribute.String("rpc.method", method))
+	defer func() {
+		metrics.Since(metrics.RPCDuration.WithLabelValues(method, metrics.Result(err)), start)
+		tracing.End(span, err)
+	}()
+
 	reqID := httputl.RequestID(ctx)
 	in := NewRequest(ctx, method, params)
 
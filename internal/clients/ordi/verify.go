@@ -15,18 +15,36 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
-	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
+)
+
+var (
+	// ErrMismatch is wrapped by the errors of the transfers not matching the Bitcoin transactions, the other errors, such
+	// as those of the Bitcoin RPC, say nothing about the transfers.
+	ErrMismatch = errors.New("Ordinals transfers mismatch")
+
+	ErrNoTransfers = errors.New("empty transfer data")
 )
 
 // TODO: High.
 // Retrieve OrdTransfer directly from the Bitcoin block using the mapping between oldSatPoint and newSatPoint,
 // bypassing the need for OrdTransfers verification.
 
-func VerifyOrdTransfer(transfers ByNewSatpoint, blockHeight uint) error {
+func VerifyOrdTransfer(ctx context.Context, btc *btcutl.Client, transfers ByNewSatpoint, blockHeight uint) (err error) {
+	ctx, span := tracing.Start(
+		ctx,
+		"ordi.VerifyOrdTransfer",
+		attribute.Int64("height", int64(blockHeight)),
+		attribute.Int("transfers", len(transfers)),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	if len(transfers) == 0 {
-		return errors.New("empty transfer data")
+		return ErrNoTransfers
 	}
 
 	transfersByID := make(map[string]ByNewSatpoint)
@@ -38,11 +56,11 @@ func VerifyOrdTransfer(transfers ByNewSatpoint, blockHeight uint) error {
 		sort.Sort(ts)
 	}
 
-	hash, err := btcutl.BTC.GetBlockHash(context.Background(), blockHeight)
+	hash, err := btc.GetBlockHash(ctx, blockHeight)
 	if err != nil {
 		return err
 	}
-	blockBody, err := btcutl.BTC.GetBlockDetail(context.Background(), hash)
+	blockBody, err := btc.GetBlockDetail(ctx, hash)
 	if err != nil {
 		return err
 	}
@@ -52,8 +70,8 @@ func VerifyOrdTransfer(transfers ByNewSatpoint, blockHeight uint) error {
 		if !found {
 			continue
 		}
-		if err := VerifyEnvelop(trans, tx); err != nil {
-			logrus.Warnf("Envelop verify failed: txid=%s, err=%v", tx.Txid, err)
+		if err := VerifyEnvelop(ctx, btc, trans, tx); err != nil {
+			logs.Warn.With("height", blockHeight, "txid", tx.Txid).Printf("Envelop verify failed: %v", err)
 			return err
 		}
 	}
@@ -61,7 +79,7 @@ func VerifyOrdTransfer(transfers ByNewSatpoint, blockHeight uint) error {
 	return nil
 }
 
-func VerifyEnvelop(transfers ByNewSatpoint, txRaw btcjson.TxRawResult) error {
+func VerifyEnvelop(ctx context.Context, btc *btcutl.Client, transfers ByNewSatpoint, txRaw btcjson.TxRawResult) error {
 	txRawBytes, err := hex.DecodeString(txRaw.Hex)
 	if err != nil {
 		return err
@@ -85,13 +103,13 @@ func VerifyEnvelop(transfers ByNewSatpoint, txRaw btcjson.TxRawResult) error {
 				continue
 			}
 			txID, _, offset := FromRawSatpoint(transfer.OldSatpoint)
-			beforeIns, err := btcutl.BTC.GetAllInscriptions(context.Background(), txID)
+			beforeIns, err := btc.GetAllInscriptions(ctx, txID)
 			if err != nil {
 				return err
 			}
 			body, found := beforeIns[transfer.InscriptionID]
 			if !found {
-				return fmt.Errorf("old inscription not found: %s", transfer.InscriptionID)
+				return fmt.Errorf("%w: old inscription not found: %s", ErrMismatch, transfer.InscriptionID)
 			}
 			allInscriptions = append(allInscriptions, Flotsam{
 				InsID:  NewInscriptionID(transfer.InscriptionID),
@@ -100,8 +118,8 @@ func VerifyEnvelop(transfers ByNewSatpoint, txRaw btcjson.TxRawResult) error {
 			})
 		}
 
-		output, err := btcutl.BTC.GetOutput(
-			context.Background(),
+		output, err := btc.GetOutput(
+			ctx,
 			txIn.PreviousOutPoint.Hash.String(),
 			int(txIn.PreviousOutPoint.Index),
 		)
@@ -153,7 +171,7 @@ func VerifyEnvelop(transfers ByNewSatpoint, txRaw btcjson.TxRawResult) error {
 			actualPkScript := string(actual.NewPkscript)
 			expectedPkScript := hex.EncodeToString(expected.TxOut.PkScript)
 			if actualPkScript != expectedPkScript {
-				return fmt.Errorf("unmatched new PkScript: actual=%s, expected=%s", actualPkScript, expectedPkScript)
+				return fmt.Errorf("%w: unmatched new PkScript: actual=%s, expected=%s", ErrMismatch, actualPkScript, expectedPkScript)
 			}
 
 			actualNewWallet := string(actual.NewWallet)
@@ -164,13 +182,13 @@ func VerifyEnvelop(transfers ByNewSatpoint, txRaw btcjson.TxRawResult) error {
 			expectedNewAddr, _ := pkscript.Address(&chaincfg.MainNetParams)
 			expectedNewWallet := expectedNewAddr.String()
 			if actualNewWallet != expectedNewWallet {
-				return fmt.Errorf("unmatched new wallet: actual=%s, expected=%s", actualNewWallet, expectedNewWallet)
+				return fmt.Errorf("%w: unmatched new wallet: actual=%s, expected=%s", ErrMismatch, actualNewWallet, expectedNewWallet)
 			}
 
 			actualContentType := actual.ContentType
 			expectedContentType := hex.EncodeToString(expected.Body.Inscription.ContentType)
 			if actualContentType != expectedContentType {
-				return fmt.Errorf("unmatched content type: actual=%s, expected=%s", actualContentType, expectedContentType)
+				return fmt.Errorf("%w: unmatched content type: actual=%s, expected=%s", ErrMismatch, actualContentType, expectedContentType)
 			}
 
 			// TODO: Low. Verify content.
@@ -186,7 +204,7 @@ func VerifyEnvelop(transfers ByNewSatpoint, txRaw btcjson.TxRawResult) error {
 	}
 
 	if p1 < len(transfers) {
-		return fmt.Errorf("invalid transfer: %+v", transfers[p1])
+		return fmt.Errorf("%w: invalid transfer: %+v", ErrMismatch, transfers[p1])
 	}
 
 	return nil
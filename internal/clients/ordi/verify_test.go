@@ -1,6 +1,7 @@
 package ordi
 
 import (
+	"context"
 	"testing"
 
 	"github.com/RiemaLabs/modular-indexer-committee/ord/getter"
@@ -9,7 +10,10 @@ import (
 )
 
 func TestVerify(t *testing.T) {
-	btcutl.Init("https://bitcoin-mainnet-archive.allthatnode.com")
+	btc, err := btcutl.New("https://bitcoin-mainnet-archive.allthatnode.com")
+	if err != nil {
+		t.Fatal(err)
+	}
 	transfers := []getter.OrdTransfer{
 		{
 			InscriptionID: "fb0d434af0bebb1808b6454614020306a5dcd49209ae463eaa58643848d344dfi0",
@@ -30,7 +34,7 @@ func TestVerify(t *testing.T) {
 			ContentType:   "746578742f706c61696e3b636861727365743d7574662d38",
 		},
 	}
-	if err := VerifyOrdTransfer(transfers, 835477); err != nil {
+	if err := VerifyOrdTransfer(context.Background(), btc, transfers, 835477); err != nil {
 		t.Fatal(err)
 	}
 }
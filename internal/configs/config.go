@@ -2,29 +2,58 @@ package configs
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/RiemaLabs/modular-indexer-committee/apis"
 	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 
+	"github.com/RiemaLabs/modular-indexer-light/internal/keystore"
 	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
 	"github.com/RiemaLabs/modular-indexer-light/internal/utils"
 	"github.com/RiemaLabs/modular-indexer-light/internal/wallet"
 )
 
-const DefaultPassword string = "light-indexer"
-
 type (
 	Config struct {
 		ListenAddr        string            `json:"listenAddr"`
+		Admin             Admin             `json:"admin"`
 		CommitteeIndexers CommitteeIndexers `json:"committeeIndexers"`
 		Verification      Verification      `json:"verification"`
 		Report            Report            `json:"report"`
+		Fraud             Fraud             `json:"fraud"`
+		Sinks             Sinks             `json:"sinks"`
+		Log               logs.Options      `json:"log"`
+		Tracing           tracing.Options   `json:"tracing"`
+		Health            Health            `json:"health"`
+	}
+
+	// Admin is the listener of the admin APIs, which change the log level, the deny list and the watch list, apart
+	// from the public APIs of listenAddr.
+	Admin struct {
+		// ListenAddr is on the loopback interface by default.
+		ListenAddr string `json:"listenAddr"`
+
+		// Token is the bearer token required by the admin APIs, required unless ListenAddr is on the loopback
+		// interface.
+		Token string `json:"token,omitempty"`
+
+		// WebhookAllowList is the IPs and CIDRs on the loopback, link-local or private networks the webhooks of the
+		// watches may target, they're rejected otherwise.
+		WebhookAllowList []string `json:"webhookAllowList,omitempty"`
 	}
 
 	CommitteeIndexers struct {
@@ -37,6 +66,26 @@ type (
 		BitcoinRPC        string `json:"bitcoinRPC"`
 		MinimalCheckpoint int    `json:"minimalCheckpoint"`
 		MetaProtocol      string `json:"metaProtocol"`
+
+		// HistorySize is the number of verified checkpoints retained for historical queries.
+		HistorySize int `json:"historySize,omitempty"`
+
+		// DenyExpiry is the period after which the deny list entries expire, zero means never.
+		DenyExpiry utils.DurH `json:"denyExpiry"`
+
+		// Signers is the hex of the x-only public keys trusted to sign the checkpoints, empty to also accept the
+		// unsigned ones. A checkpoint with an invalid signature is always rejected.
+		Signers []string `json:"signers"`
+	}
+
+	// Health is the thresholds of the health and readiness checks, zero values mean the defaults.
+	Health struct {
+		// MaxLagBlocks is the maximal number of blocks the verified height could be behind the Bitcoin tip, nil means
+		// the default and zero requires the verified height to be the tip.
+		MaxLagBlocks *uint `json:"maxLagBlocks,omitempty"`
+
+		// MaxSyncInterval is the maximal time since the sync loop was last alive.
+		MaxSyncInterval utils.DurH `json:"maxSyncInterval"`
 	}
 
 	Report struct {
@@ -46,16 +95,71 @@ type (
 		GasCoupon   string     `json:"gasCoupon"`
 		Timeout     utils.DurH `json:"timeout"`
 
+		// NamespaceName is the name of the namespace to create, or to reuse if the key already owns one of the name,
+		// when the namespace ID is empty.
+		NamespaceName string `json:"namespaceName,omitempty"`
+
+		// Jitter is the maximal random delay of uploading a checkpoint, so the Light Indexers don't upload at once.
+		Jitter utils.DurH `json:"jitter"`
+
+		// MaxBackoff is the maximal interval of retrying a failed upload.
+		MaxBackoff utils.DurH `json:"maxBackoff"`
+
 		// PrivateKey loaded from files.
 		PrivateKey string `json:"-"`
 	}
 )
 
+// Sinks are the targets to republish the verified checkpoints to besides DA, named by `report.name`.
+type Sinks struct {
+	S3      []SinkS3 `json:"s3"`
+	Webhook []string `json:"webhook"`
+	File    []string `json:"file"`
+}
+
+// SinkS3 is an S3 compatible bucket, the checkpoints are named the same as those read by the S3 committee indexers.
+type SinkS3 struct {
+	Region string `json:"region"`
+	Bucket string `json:"bucket"`
+
+	// Endpoint of the S3 compatible service, empty for AWS S3.
+	Endpoint  string `json:"endpoint,omitempty"`
+	PathStyle bool   `json:"pathStyle,omitempty"`
+
+	// The static credentials, empty to use the default credentials of AWS SDK such as AWS_ACCESS_KEY_ID.
+	AccessKeyID     string `json:"accessKeyID,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+}
+
+// Enabled reports whether there is any sink.
+func (s *Sinks) Enabled() bool {
+	return len(s.S3)+len(s.Webhook)+len(s.File) > 0
+}
+
+// Fraud is publishing the fraud proofs of the denied committee indexers and importing those published by others. The
+// fraud proofs are uploaded to DA by the report key on the report network.
+type Fraud struct {
+	// NamespaceID of DA to publish the fraud proofs to, empty to disable.
+	NamespaceID string `json:"namespaceID"`
+
+	// Webhook to POST the fraud reports to, e.g. `/admin/fraud_reports` of another Light Indexer, empty to disable.
+	Webhook string `json:"webhook"`
+
+	// Import is the namespace IDs of DA to import the fraud reports from by `deny import`.
+	Import []string `json:"import"`
+}
+
 type (
+	// DenyList is an entry of the deny list, the committee indexer of the source is excluded from the verification.
 	DenyList struct {
+		// Evidence is nil for the entries added by hand.
 		Evidence *Evidence `json:"evidence"`
 		SourceS3 *SourceS3 `json:"sourceS3"`
 		SourceDA *SourceDA `json:"sourceDa"`
+
+		// CreatedAt is zero for the entries written by previous versions, which never expire.
+		CreatedAt time.Time `json:"createdAt"`
+		Reason    string    `json:"reason,omitempty"`
 	}
 
 	Evidence struct {
@@ -63,9 +167,49 @@ type (
 		Hash              string `json:"hash"`
 		CorrectCommitment string `json:"correctCommitment"`
 		FraudCommitment   string `json:"fraudCommitment"`
+
+		// Proof is nil if the committee indexer didn't serve a state proof, or for the entries written by previous
+		// versions.
+		Proof *FraudProof `json:"proof,omitempty"`
+	}
+
+	// FraudProof is a self-contained bundle to re-check a failed commitment: applying the state proof, with its Ordinals
+	// transfers, to the previous commitment must not produce the claimed commitment. It doesn't prove the committee
+	// indexer served the state proof, so it's only as trustworthy as its origin.
+	FraudProof struct {
+		Height         uint   `json:"height"`
+		Hash           string `json:"hash"`
+		PrevCommitment string `json:"prevCommitment"`
+
+		// Commitment is the one claimed by the committee indexer, and RecomputedCommitment is the root recomputed from
+		// the state proof, empty if the state proof couldn't be applied.
+		Commitment           string `json:"commitment"`
+		RecomputedCommitment string `json:"recomputedCommitment,omitempty"`
+
+		Failure Failure `json:"failure"`
+		Message string  `json:"message,omitempty"`
+
+		// Name and URL of the committee indexer serving the state proof.
+		Name       string                                        `json:"name"`
+		URL        string                                        `json:"url"`
+		StateProof *apis.Brc20VerifiableLatestStateProofResponse `json:"stateProof"`
 	}
 )
 
+// Failure of a commitment in a fraud proof.
+type Failure string
+
+const (
+	// FailureCommitmentMismatch means the recomputed commitment differs from the claimed one.
+	FailureCommitmentMismatch Failure = "commitment-mismatch"
+
+	// FailureInvalidTransfers means the Ordinals transfers of the state proof don't match Bitcoin.
+	FailureInvalidTransfers Failure = "invalid-transfers"
+
+	// FailureInvalidProof means the state proof couldn't be applied to the previous commitment.
+	FailureInvalidProof Failure = "invalid-proof"
+)
+
 type (
 	SourceS3 struct {
 		Region string `json:"region"`
@@ -99,87 +243,241 @@ type CheckpointExport struct {
 	Checkpoint *checkpoint.Checkpoint `json:"checkPoint"`
 	SourceS3   *SourceS3              `json:"sourceS3,omitempty"`
 	SourceDA   *SourceDA              `json:"sourceDa,omitempty"`
+
+	// Signer is the public key of the verified signature of the checkpoint, empty if it's unsigned.
+	Signer string `json:"signer,omitempty"`
 }
 
+// C is the config the light indexer started with, see Applied for the fields reloaded at runtime.
 var C *Config
 
-func ReadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+var applied atomic.Pointer[Config]
+
+// Applied returns the last applied config, C until the first reload. Its committee indexers and verification fields
+// are in effect, the others only take effect after a restart.
+func Applied() *Config {
+	if c := applied.Load(); c != nil {
+		return c
 	}
+	return C
+}
 
-	var c Config
-	if err := json.Unmarshal(data, &c); err != nil {
-		return nil, err
+// Apply swaps in the reloaded config for Applied.
+func Apply(c *Config) {
+	applied.Store(c)
+}
+
+// ReadConfig reads the config file in the format of its extension with the overrides, empty path means the config is from the overrides only.
+func ReadConfig(path string, overrides ...*Override) (*Config, error) {
+	var data []byte
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+		if data, err = FormatOf(path).toJSON(data); err != nil {
+			return nil, fmt.Errorf("%s: invalid config: %v", path, err)
+		}
 	}
 
-	return &c, nil
+	c, err := ParseConfig(data, overrides...)
+	if err != nil {
+		if path == "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
 }
 
+// ReadDenyList reads the entries of the deny list, a missing file means an empty list. Invalid lines, e.g. one
+// partially written on a crash, are skipped with warnings.
 func ReadDenyList(path string) ([]*DenyList, error) {
 	f, err := os.Open(path)
-	if err != nil {
+	if errors.Is(err, os.ErrNotExist) {
 		return nil, nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("open deny list error: %v", err)
+	}
 	defer func() { _ = f.Close() }()
 
 	var ret []*DenyList
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		fmt.Println()
-		data := []byte(strings.TrimSpace(scanner.Text()))
-		var item DenyList
-		if err := json.Unmarshal(data, &item); err != nil {
-			logs.Warn.Printf("Parse deny list error: loaded=%d, err=%v", len(ret), err)
+	// Lines may be long with the evidence, so they are not limited by the buffer size of a bufio.Scanner.
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("read deny list error: line=%d, err=%v", line, err)
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var item DenyList
+			if err := json.Unmarshal(data, &item); err != nil {
+				logs.Warn.Printf("Invalid deny list entry skipped: path=%s, line=%d, err=%v", path, line, err)
+			} else if item.SourceS3 == nil && item.SourceDA == nil {
+				logs.Warn.Printf("Deny list entry without source skipped: path=%s, line=%d", path, line)
+			} else {
+				ret = append(ret, &item)
+			}
+		}
+		if err != nil {
 			return ret, nil
 		}
-		ret = append(ret, &item)
-	}
-	if err := scanner.Err(); err != nil {
-		logs.Warn.Printf("Scan deny list error: loaded=%d, err=%v", len(ret), err)
 	}
-
-	return ret, nil
 }
 
-func (r *Report) LoadPrivate(path string) error {
-	if data, err := os.ReadFile(path); err == nil {
-		r.PrivateKey = string(data)
+// Passphrase returns the passphrase of the private key file, confirm is set for a new one so it could be asked twice.
+type Passphrase func(confirm bool) ([]byte, error)
+
+// LoadPrivate reads the private key from the encrypted file, or generates one if the file doesn't exist. A plaintext
+// file of the previous versions is encrypted in place.
+func (r *Report) LoadPrivate(path string, passphrase Passphrase) error {
+	key, err := ReadPrivate(path, passphrase)
+	if err == nil {
+		r.PrivateKey = key
 		return nil
 	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
 
 	logs.Info.Printf("Failed to read the private key from the local directory, generating a new one...")
-	pwd := DefaultPassword
-
-	wall := wallet.NewWallet(&pwd)
-	if !wall.GenerateBip39Seed(&pwd, &pwd) {
-		return errors.New("failed to generate BIP39 seed")
+	pass, err := passphrase(true)
+	if err != nil {
+		return err
 	}
-	account := wall.GenerateAccount(&pwd)
-
-	key := utils.EcdsaToPrivateStr(account.PrivateKey(&pwd))
-	if err := os.WriteFile(path, []byte(key), 0644); err != nil {
+	pwd := string(pass)
+	key, backup, err := WalletPrivate(wallet.NewWallet(&pwd), pwd)
+	if err != nil {
+		return err
+	}
+	if err := WritePrivate(path, key, backup, passphrase); err != nil {
 		return fmt.Errorf("write private key to file error: %v", err)
 	}
 
-	logs.Info.Printf("Store your private file %q carefully and don't share it!", path)
+	logs.Info.Printf("Store your private file %q and its passphrase carefully and don't share them!", path)
 	r.PrivateKey = key
 
 	return nil
 }
 
-func Init(configPath, denyListPath string) error {
-	c, err := ReadConfig(configPath)
+// WalletPrivate derives the private key in hex of the first SEP-0005 account of the wallet encrypted by the password,
+// with no mnemonic password so the mnemonic alone restores it. The backup is the wallet exported before deriving the
+// account, so it only holds the encrypted seeds but not the private key.
+func WalletPrivate(w *wallet.Wallet, password string) (key string, backup []byte, err error) {
+	mnemonicPwd := ""
+	if !w.GenerateBip39Seed(&password, &mnemonicPwd) && w.GetSeed(&password) == nil {
+		return "", nil, errors.New("failed to generate BIP39 seed, wrong wallet password?")
+	}
+	backup = w.ExportBinary()
+	account := w.GenerateAccount(&password)
+	if account == nil {
+		return "", nil, errors.New("failed to generate wallet account")
+	}
+	return utils.EcdsaToPrivateStr(account.PrivateKey(&password)), backup, nil
+}
+
+// PrivatePublicKey returns the x-only public key in hex of the private key in hex, which verifies its signatures.
+func PrivatePublicKey(key string) (string, error) {
+	data, err := hex.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %v", err)
+	}
+	pk, _ := btcec.PrivKeyFromBytes(data)
+	return hex.EncodeToString(schnorr.SerializePubKey(pk.PubKey())), nil
+}
+
+// ReadPrivate reads the private key in hex from the encrypted file. A plaintext file of the previous versions is
+// encrypted in place.
+func ReadPrivate(path string, passphrase Passphrase) (string, error) {
+	key, _, err := ReadPrivateWallet(path, passphrase)
+	return key, err
+}
+
+// ReadPrivateWallet reads the private key in hex and the wallet backup it's derived from, nil if none, from the
+// encrypted file. A plaintext file or a wallet in the legacy format of the previous versions is upgraded in place.
+func ReadPrivateWallet(path string, passphrase Passphrase) (key string, backup []byte, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if keystore.IsPlaintext(data) {
+		key := strings.TrimPrefix(strings.TrimSpace(string(data)), "0x")
+		logs.Warn.Printf("Private key file %q is in plaintext, encrypting it...", path)
+		if err := WritePrivate(path, key, nil, passphrase); err != nil {
+			return "", nil, fmt.Errorf("encrypt plaintext private key error: path=%s, err=%v", path, err)
+		}
+		return key, nil, nil
+	}
+
+	ks, err := keystore.Read(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("read private key error: path=%s, err=%v", path, err)
+	}
+	pass, err := passphrase(false)
+	if err != nil {
+		return "", nil, err
+	}
+	raw, backup, err := ks.Decrypt(pass)
+	if err != nil {
+		return "", nil, fmt.Errorf("decrypt private key error: path=%s, err=%v", path, err)
+	}
+	key = hex.EncodeToString(raw)
+	if backup != nil && wallet.IsV1(backup) {
+		logs.Info.Printf("Wallet of private key file %q is in the legacy format, upgrading it...", path)
+		pwd := string(pass)
+		w, err := wallet.ImportBinary(backup, &pwd)
+		if err != nil {
+			return "", nil, fmt.Errorf("read legacy wallet error: path=%s, err=%v", path, err)
+		}
+		backup = w.ExportBinary()
+		if err := WritePrivate(path, key, backup, passphrase); err != nil {
+			return "", nil, fmt.Errorf("upgrade legacy wallet error: path=%s, err=%v", path, err)
+		}
+	}
+	return key, backup, nil
+}
+
+// WritePrivate encrypts the private key in hex and the wallet backup it's derived from, nil if none, to the file,
+// readable only by the owner.
+func WritePrivate(path, key string, backup []byte, passphrase Passphrase) error {
+	data, err := hex.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("invalid private key: %v", err)
+	}
+	publicKey, err := PrivatePublicKey(key)
+	if err != nil {
+		return err
+	}
+	pass, err := passphrase(true)
 	if err != nil {
 		return err
 	}
+	ks, err := keystore.Encrypt(data, backup, pass)
+	if err != nil {
+		return err
+	}
+	ks.Address = utils.PrivateStrToBtcAddress(key)
+	ks.PublicKey = publicKey
+	return keystore.Write(path, ks)
+}
+
+// Load reads the config with the overrides, and removes the committee indexers in the deny list.
+func Load(configPath, denyListPath string, overrides ...*Override) (*Config, error) {
+	c, err := ReadConfig(configPath, overrides...)
+	if err != nil {
+		return nil, err
+	}
 
 	denials, err := ReadDenyList(denyListPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	now := time.Now()
 	for _, b := range denials {
+		if b.Expired(c.Verification.DenyExpiry.Duration, now) {
+			continue
+		}
 		if d := b.SourceDA; d != nil {
 			c.CommitteeIndexers.DA = slices.DeleteFunc(c.CommitteeIndexers.DA, func(s SourceDA) bool { return s.Equal(d) })
 		}
@@ -187,23 +485,14 @@ func Init(configPath, denyListPath string) error {
 			c.CommitteeIndexers.S3 = slices.DeleteFunc(c.CommitteeIndexers.S3, func(s SourceS3) bool { return s.Equal(d) })
 		}
 	}
-
-	C = c
-	return nil
+	return c, nil
 }
 
-func AppendDenyList(path string, item *DenyList) error {
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+func Init(configPath, denyListPath string, overrides ...*Override) error {
+	c, err := Load(configPath, denyListPath, overrides...)
 	if err != nil {
-		return fmt.Errorf("open error: %v", err)
-	}
-	defer func() { _ = f.Close() }()
-
-	data, err := json.Marshal(item)
-	if err != nil {
-		return fmt.Errorf("marshal error: %v", err)
+		return err
 	}
-
-	_, err = f.WriteString(string(data) + "\n")
-	return err
+	C = c
+	return nil
 }
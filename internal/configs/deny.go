@@ -0,0 +1,181 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Name returns the name of the denied committee indexer.
+func (d *DenyList) Name() string {
+	if d.SourceS3 != nil {
+		return d.SourceS3.Name
+	}
+	if d.SourceDA != nil {
+		return d.SourceDA.Name
+	}
+	return ""
+}
+
+// ExpiresAt returns when the entry expires, zero means never.
+func (d *DenyList) ExpiresAt(expiry time.Duration) time.Time {
+	if expiry <= 0 || d.CreatedAt.IsZero() {
+		return time.Time{}
+	}
+	return d.CreatedAt.Add(expiry)
+}
+
+// Expired reports whether the entry has expired at the time.
+func (d *DenyList) Expired(expiry time.Duration, now time.Time) bool {
+	at := d.ExpiresAt(expiry)
+	return !at.IsZero() && !now.Before(at)
+}
+
+// NextExpiry returns when the earliest of the unexpired entries expires, zero means none of them would.
+func NextExpiry(items []*DenyList, expiry time.Duration, now time.Time) time.Time {
+	var next time.Time
+	for _, d := range items {
+		at := d.ExpiresAt(expiry)
+		if at.IsZero() || !now.Before(at) {
+			continue
+		}
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	return next
+}
+
+// NewDenial creates an entry without evidence to deny the committee indexer of the name.
+func (c *CommitteeIndexers) NewDenial(name, reason string) (*DenyList, error) {
+	d := &DenyList{CreatedAt: time.Now().UTC(), Reason: reason}
+	for i := range c.S3 {
+		if c.S3[i].Name == name {
+			s := c.S3[i]
+			d.SourceS3 = &s
+			return d, nil
+		}
+	}
+	for i := range c.DA {
+		if c.DA[i].Name == name {
+			s := c.DA[i]
+			d.SourceDA = &s
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("committee indexer not found: name=%s", name)
+}
+
+// denyListMu serializes the changes of the deny lists by this process, and the lock file those by other processes
+// like the deny subcommands.
+var denyListMu sync.Mutex
+
+// lockDenyList locks the deny list against the other changes until unlock is called. The lock is taken on a file next
+// to the deny list, since the deny list itself is replaced by rewrites.
+func lockDenyList(path string) (unlock func(), err error) {
+	denyListMu.Lock()
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		denyListMu.Unlock()
+		return nil, fmt.Errorf("open deny list lock error: %v", err)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		denyListMu.Unlock()
+		return nil, fmt.Errorf("lock deny list error: %v", err)
+	}
+	return func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+		denyListMu.Unlock()
+	}, nil
+}
+
+// AppendDenyList appends the entry to the deny list.
+func AppendDenyList(path string, item *DenyList) error {
+	unlock, err := lockDenyList(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("open error: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("marshal error: %v", err)
+	}
+
+	_, err = f.WriteString(string(data) + "\n")
+	return err
+}
+
+// WriteDenyList replaces the entries of the deny list, the file is replaced at once so readers never see a partial one.
+func WriteDenyList(path string, items []*DenyList) error {
+	unlock, err := lockDenyList(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeDenyList(path, items)
+}
+
+func writeDenyList(path string, items []*DenyList) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create temporary file error: %v", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("write deny list error: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write deny list error: %v", err)
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// RemoveDenyList removes the matched entries from the deny list and returns them.
+func RemoveDenyList(path string, match func(*DenyList) bool) ([]*DenyList, error) {
+	unlock, err := lockDenyList(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	items, err := ReadDenyList(path)
+	if err != nil {
+		return nil, err
+	}
+	var kept, removed []*DenyList
+	for _, item := range items {
+		if match(item) {
+			removed = append(removed, item)
+		} else {
+			kept = append(kept, item)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	if err := writeDenyList(path, kept); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
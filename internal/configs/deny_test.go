@@ -0,0 +1,120 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDenyList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deny.jsonlines")
+	if items, err := ReadDenyList(path); err != nil || len(items) != 0 {
+		t.Fatal("expected empty deny list of a missing file", items, err)
+	}
+
+	indexers := &CommitteeIndexers{
+		S3: []SourceS3{{Region: "us-west-2", Bucket: "b", Name: "s3"}},
+		DA: []SourceDA{{Network: "n", NamespaceID: "0x00000001", Name: "da"}},
+	}
+	if _, err := indexers.NewDenial("unknown", ""); err == nil {
+		t.Fatal("expected unknown committee indexer error")
+	}
+	old, err := indexers.NewDenial("s3", "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.CreatedAt = time.Now().Add(-2 * time.Hour)
+	legacy, err := indexers.NewDenial("da", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.CreatedAt = time.Time{}
+	for _, d := range []*DenyList{old, legacy} {
+		if err := AppendDenyList(path, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A partially written line doesn't hide the entries after it.
+	if err := appendRaw(path, `{"sourceS3": {"name": "bro`); err != nil {
+		t.Fatal(err)
+	}
+	recent, _ := indexers.NewDenial("s3", "recent")
+	if err := AppendDenyList(path, recent); err != nil {
+		t.Fatal(err)
+	}
+	items, err := ReadDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[2].Reason != "recent" {
+		t.Fatalf("unexpected entries: %d", len(items))
+	}
+
+	now := time.Now()
+	if !items[0].Expired(time.Hour, now) || items[1].Expired(time.Hour, now) || items[2].Expired(time.Hour, now) {
+		t.Fatal("unexpected expiration")
+	}
+	if items[0].Expired(0, now) {
+		t.Fatal("entries never expire without expiry")
+	}
+	if next := NextExpiry(items, time.Hour, now); !next.Equal(items[2].CreatedAt.Add(time.Hour)) {
+		t.Fatal("unexpected next expiry", next)
+	}
+
+	removed, err := RemoveDenyList(path, func(d *DenyList) bool { return d.Expired(time.Hour, now) })
+	if err != nil || len(removed) != 1 || removed[0].Reason != "old" {
+		t.Fatal("unexpected removed entries", removed, err)
+	}
+	if items, err = ReadDenyList(path); err != nil || len(items) != 2 {
+		t.Fatal("unexpected entries after removal", items, err)
+	}
+}
+
+func appendRaw(path, line string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	_, err = f.WriteString(line + "\n")
+	return err
+}
+
+func TestDenyList_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deny.jsonlines")
+	indexers := &CommitteeIndexers{S3: []SourceS3{{Name: "kept"}, {Name: "removed"}}}
+	removed, _ := indexers.NewDenial("removed", "")
+	if err := AppendDenyList(path, removed); err != nil {
+		t.Fatal(err)
+	}
+
+	// An entry appended while the deny list is being rewritten isn't lost.
+	var wg sync.WaitGroup
+	_, err := RemoveDenyList(path, func(d *DenyList) bool {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			kept, _ := indexers.NewDenial("kept", "")
+			if err := AppendDenyList(path, kept); err != nil {
+				t.Error(err)
+			}
+		}()
+		time.Sleep(50 * time.Millisecond)
+		return d.Name() == "removed"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	items, err := ReadDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Name() != "kept" {
+		t.Fatal("unexpected entries", items)
+	}
+}
@@ -0,0 +1,388 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Format of the config file.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// FormatOf returns the format of the config file by its extension, JSON by default.
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// toJSON converts the config to JSON, so every format shares the JSON field names and decoding.
+func (f Format) toJSON(data []byte) ([]byte, error) {
+	var v any
+	switch f {
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+	default:
+		return data, nil
+	}
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// WriteField sets a string field of the config file by its JSON path like `report.namespaceID`. The value is edited in
+// place, so the other fields, the formatting and the comments are kept as they are in the file.
+func WriteField(path, field, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	format := FormatOf(path)
+	keys := strings.Split(field, ".")
+	var edited []byte
+	switch format {
+	case FormatYAML:
+		edited, err = setYAML(data, keys, value)
+	case FormatTOML:
+		edited, err = setTOML(data, keys, value)
+	default:
+		edited, err = setJSON(data, keys, value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: set %s error: %v", path, field, err)
+	}
+	if err := checkField(format, edited, keys, value); err != nil {
+		return fmt.Errorf("%s: set %s error: %v", path, field, err)
+	}
+	return os.WriteFile(path, edited, 0644)
+}
+
+// checkField makes sure the edited config is still valid in its format and has the value.
+func checkField(format Format, data []byte, keys []string, value string) error {
+	data, err := format.toJSON(data)
+	if err != nil {
+		return fmt.Errorf("invalid edited config: %v", err)
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid edited config: %v", err)
+	}
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return errors.New("field not found in the edited config")
+		}
+		v = m[k]
+	}
+	if v != value {
+		return fmt.Errorf("unexpected value in the edited config: %v", v)
+	}
+	return nil
+}
+
+func splice(data []byte, start, end int, s string) []byte {
+	ret := make([]byte, 0, len(data)-(end-start)+len(s))
+	ret = append(ret, data[:start]...)
+	ret = append(ret, s...)
+	return append(ret, data[end:]...)
+}
+
+// quote quotes the string in JSON, which is also a valid double-quoted string of YAML and TOML.
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func setJSON(data []byte, keys []string, value string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, errors.New("config is not an object")
+	}
+
+	for i := range keys {
+		open := int(dec.InputOffset())
+		found := false
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if t != keys[i] {
+				var skipped json.RawMessage
+				if err := dec.Decode(&skipped); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if i == len(keys)-1 {
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					return nil, err
+				}
+				end := int(dec.InputOffset())
+				return splice(data, end-len(raw), end, quote(value)), nil
+			}
+			if t, err := dec.Token(); err != nil {
+				return nil, err
+			} else if t != json.Delim('{') {
+				return nil, fmt.Errorf("%s is not an object", strings.Join(keys[:i+1], "."))
+			}
+			found = true
+			break
+		}
+		if found {
+			continue
+		}
+
+		// Insert the missing member as the first one of the object, with the indent of the existing members.
+		nested := quote(value)
+		for j := len(keys) - 1; j > i; j-- {
+			nested = "{" + quote(keys[j]) + ": " + nested + "}"
+		}
+		member := quote(keys[i]) + ": " + nested
+		rest := data[open:]
+		trimmed := bytes.TrimLeft(rest, " \t\r\n")
+		if bytes.HasPrefix(trimmed, []byte("}")) {
+			return splice(data, open, open, member), nil
+		}
+		return splice(data, open, open, string(rest[:len(rest)-len(trimmed)])+member+","), nil
+	}
+	return nil, errors.New("unreachable")
+}
+
+func setTOML(data []byte, keys []string, value string) ([]byte, error) {
+	parent := strings.Join(keys[:len(keys)-1], ".")
+	field := strings.Join(keys, ".")
+
+	var (
+		p       unstable.Parser
+		table   string
+		inArray bool
+		// parentEnd is the offset after the header of the parent table, or -1 if there isn't one.
+		parentEnd = -1
+	)
+	p.Reset(data)
+	for p.NextExpression() {
+		e := p.Expression()
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			var last *unstable.Node
+			table, last = tomlKey(e)
+			inArray = e.Kind == unstable.ArrayTable
+			if !inArray && table == parent {
+				parentEnd = lineEnd(data, int(last.Raw.Offset))
+			}
+		case unstable.KeyValue:
+			k, _ := tomlKey(e)
+			if table != "" {
+				k = table + "." + k
+			}
+			if inArray || k != field {
+				continue
+			}
+			v := e.Value()
+			if v.Kind != unstable.String {
+				return nil, fmt.Errorf("%s is not a string", field)
+			}
+			start := int(v.Raw.Offset)
+			return splice(data, start, start+int(v.Raw.Length), quote(value)), nil
+		}
+	}
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+
+	line := keys[len(keys)-1] + " = " + quote(value) + "\n"
+	switch {
+	case parentEnd >= 0:
+		return splice(data, parentEnd, parentEnd, line), nil
+	case parent == "":
+		// Keys before any table header belong to the root table.
+		return splice(data, 0, 0, line), nil
+	default:
+		sep := "\n"
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			sep = "\n\n"
+		}
+		return splice(data, len(data), len(data), sep+"["+parent+"]\n"+line), nil
+	}
+}
+
+// tomlKey returns the dotted key of a table or key-value expression and its last part.
+func tomlKey(e *unstable.Node) (string, *unstable.Node) {
+	var (
+		parts []string
+		last  *unstable.Node
+	)
+	it := e.Key()
+	for it.Next() {
+		last = it.Node()
+		parts = append(parts, string(last.Data))
+	}
+	return strings.Join(parts, "."), last
+}
+
+// lineEnd returns the offset after the line containing the offset.
+func lineEnd(data []byte, offset int) int {
+	if i := bytes.IndexByte(data[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(data)
+}
+
+func setYAML(data []byte, keys []string, value string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		sep := ""
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			sep = "\n"
+		}
+		return splice(data, len(data), len(data), sep+yamlBlock(keys, value, "")), nil
+	}
+
+	m := doc.Content[0]
+	for i, k := range keys {
+		if m.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a mapping", strings.Join(keys[:i], "."))
+		}
+		var key, v *yaml.Node
+		for j := 0; j+1 < len(m.Content); j += 2 {
+			if m.Content[j].Value == k {
+				key, v = m.Content[j], m.Content[j+1]
+			}
+		}
+
+		if v == nil {
+			// Insert the missing key before the first one of the mapping, with the same indent.
+			if m.Style&yaml.FlowStyle != 0 || len(m.Content) == 0 {
+				return nil, fmt.Errorf("unsupported flow mapping at line %d", m.Line)
+			}
+			first := m.Content[0]
+			start := yamlOffset(data, first.Line, 1)
+			indent := data[start:yamlOffset(data, first.Line, first.Column)]
+			if len(bytes.TrimLeft(indent, " ")) > 0 {
+				return nil, fmt.Errorf("unsupported mapping at line %d", first.Line)
+			}
+			return splice(data, start, start, yamlBlock(keys[i:], value, string(indent))), nil
+		}
+		if i < len(keys)-1 {
+			m = v
+			continue
+		}
+
+		if v.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s is not a scalar", strings.Join(keys, "."))
+		}
+		if v.Tag == "!!null" && v.Value == "" {
+			// The value is empty like `namespaceID:`, insert it after the colon.
+			at := yamlOffset(data, key.Line, key.Column)
+			colon := bytes.IndexByte(data[at:], ':')
+			if colon < 0 {
+				return nil, fmt.Errorf("unsupported key at line %d", key.Line)
+			}
+			return splice(data, at+colon+1, at+colon+1, " "+quote(value)), nil
+		}
+		start := yamlOffset(data, v.Line, v.Column)
+		end, err := yamlScalarEnd(data, start, v.Style)
+		if err != nil {
+			return nil, fmt.Errorf("%v at line %d", err, v.Line)
+		}
+		return splice(data, start, end, quote(value)), nil
+	}
+	return nil, errors.New("unreachable")
+}
+
+// yamlBlock returns the block mapping of the nested keys with the value.
+func yamlBlock(keys []string, value, indent string) string {
+	var b strings.Builder
+	for i, k := range keys {
+		b.WriteString(indent + strings.Repeat("  ", i) + k + ":")
+		if i == len(keys)-1 {
+			b.WriteString(" " + quote(value))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// yamlOffset returns the offset of the 1-based line and column in characters.
+func yamlOffset(data []byte, line, column int) int {
+	offset := 0
+	for ; line > 1; line-- {
+		offset = lineEnd(data, offset)
+	}
+	for ; column > 1 && offset < len(data); column-- {
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// yamlScalarEnd returns the offset after a single-line scalar.
+func yamlScalarEnd(data []byte, start int, style yaml.Style) (int, error) {
+	switch style {
+	case yaml.DoubleQuotedStyle:
+		for i := start + 1; i < len(data); i++ {
+			switch data[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, nil
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := start + 1; i < len(data); i++ {
+			if data[i] != '\'' {
+				continue
+			}
+			if i+1 < len(data) && data[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	case 0:
+		end := lineEnd(data, start)
+		line := data[start:end]
+		if i := bytes.Index(line, []byte(" #")); i >= 0 {
+			line = line[:i]
+		}
+		return start + len(bytes.TrimRight(line, " \t\r\n")), nil
+	default:
+		return 0, errors.New("unsupported block scalar")
+	}
+	return 0, errors.New("unterminated quoted scalar")
+}
@@ -0,0 +1,163 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	exampleYAML = `# Light indexer
+listenAddr: ":8080"
+committeeIndexers:
+  s3:
+    - region: us-west-2 # the bucket region
+      bucket: nubit-modular-indexer-brc-20
+      name: nubit-official-00
+verification:
+  bitcoinRPC: https://bitcoin-mainnet-archive.allthatnode.com
+  minimalCheckpoint: 1
+report:
+  name: light
+  network: Nubit Alpha Testnet
+  namespaceID: '' # created on the first run
+  gasCoupon: secret
+  timeout: 15s
+`
+	exampleTOML = `# Light indexer
+listenAddr = ":8080"
+
+[[committeeIndexers.s3]]
+region = "us-west-2" # the bucket region
+bucket = "nubit-modular-indexer-brc-20"
+name = "nubit-official-00"
+
+[verification]
+bitcoinRPC = "https://bitcoin-mainnet-archive.allthatnode.com"
+minimalCheckpoint = 1
+
+[report]
+name = "light"
+network = "Nubit Alpha Testnet"
+namespaceID = "" # created on the first run
+gasCoupon = "secret"
+timeout = "15s"
+`
+	exampleJSON = `{
+    "listenAddr": ":8080",
+    "committeeIndexers": {
+        "s3": [{"region": "us-west-2", "bucket": "nubit-modular-indexer-brc-20", "name": "nubit-official-00"}]
+    },
+    "verification": {"bitcoinRPC": "https://bitcoin-mainnet-archive.allthatnode.com", "minimalCheckpoint": 1},
+    "report": {
+        "name": "light",
+        "network": "Nubit Alpha Testnet",
+        "namespaceID": "",
+        "gasCoupon": "secret",
+        "timeout": "15s"
+    }
+}
+`
+)
+
+func writeConfig(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadConfig_Formats(t *testing.T) {
+	expected, err := ReadConfig(writeConfig(t, "config.json", exampleJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"config.yaml": exampleYAML, "config.yml": exampleYAML, "config.toml": exampleTOML} {
+		c, err := ReadConfig(writeConfig(t, name, data))
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if !reflect.DeepEqual(c, expected) {
+			t.Fatalf("%s: unexpected config: %+v", name, c)
+		}
+	}
+
+	if _, err := ReadConfig(writeConfig(t, "config.yaml", exampleYAML+"unknown: 1\n")); err == nil {
+		t.Fatal("expected unknown field error")
+	}
+}
+
+func TestWriteField(t *testing.T) {
+	const nid = "0x00000001"
+	for _, tt := range []struct{ name, data, expected string }{
+		{
+			"replace.json",
+			`{"report": {"name": "a", "namespaceID": ""}}`,
+			`{"report": {"name": "a", "namespaceID": "0x00000001"}}`,
+		},
+		{
+			"insert.json",
+			"{\n  \"report\": {\n    \"name\": \"a\"\n  }\n}\n",
+			"{\n  \"report\": {\n    \"namespaceID\": \"0x00000001\",\n    \"name\": \"a\"\n  }\n}\n",
+		},
+		{
+			"insert-parent.json",
+			`{"listenAddr": ":8080"}`,
+			`{"report": {"namespaceID": "0x00000001"},"listenAddr": ":8080"}`,
+		},
+		{
+			"replace.yaml",
+			"report:\n  # the namespace\n  namespaceID: '' # created on the first run\n  name: a\n",
+			"report:\n  # the namespace\n  namespaceID: \"0x00000001\" # created on the first run\n  name: a\n",
+		},
+		{
+			"empty.yaml",
+			"report:\n  namespaceID:\n  name: a\n",
+			"report:\n  namespaceID: \"0x00000001\"\n  name: a\n",
+		},
+		{
+			"insert.yaml",
+			"# config\nreport:\n  name: a # the name\n",
+			"# config\nreport:\n  namespaceID: \"0x00000001\"\n  name: a # the name\n",
+		},
+		{
+			"insert-parent.yaml",
+			"listenAddr: :8080\n",
+			"report:\n  namespaceID: \"0x00000001\"\nlistenAddr: :8080\n",
+		},
+		{
+			"replace.toml",
+			"[report]\nname = 'a'\nnamespaceID = '' # created on the first run\n",
+			"[report]\nname = 'a'\nnamespaceID = \"0x00000001\" # created on the first run\n",
+		},
+		{
+			"insert.toml",
+			"[report] # report\nname = 'a'\n\n[log]\nlevel = 'info'\n",
+			"[report] # report\nnamespaceID = \"0x00000001\"\nname = 'a'\n\n[log]\nlevel = 'info'\n",
+		},
+		{
+			"insert-parent.toml",
+			"listenAddr = ':8080'",
+			"listenAddr = ':8080'\n\n[report]\nnamespaceID = \"0x00000001\"\n",
+		},
+	} {
+		path := writeConfig(t, tt.name, tt.data)
+		if err := WriteField(path, "report.namespaceID", nid); err != nil {
+			t.Fatal(tt.name, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.expected {
+			t.Fatalf("%s: unexpected config file:\n%s", tt.name, data)
+		}
+	}
+
+	path := writeConfig(t, "config.yaml", "report: [1]\n")
+	if err := WriteField(path, "report.namespaceID", nid); err == nil {
+		t.Fatal("expected error of non-mapping parent")
+	}
+}
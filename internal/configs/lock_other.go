@@ -0,0 +1,10 @@
+//go:build js || windows
+
+package configs
+
+import "os"
+
+// lockFile is a no-op on this platform, the changes are only serialized within the process.
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
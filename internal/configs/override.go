@@ -0,0 +1,151 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding config fields.
+const EnvPrefix = "LIGHT_"
+
+// EnvFileSuffix is the suffix of the environment variables reading a config field from a file, e.g. secrets mounted
+// by the container runtime.
+const EnvFileSuffix = "_FILE"
+
+// Field is an overridable config field.
+type Field struct {
+	// Path is the JSON path like `verification.bitcoinRPC`.
+	Path string
+
+	// Env is the environment variable like `LIGHT_VERIFICATION_BITCOIN_RPC`.
+	Env string
+
+	// Flag is the command-line flag like `verification.bitcoin-rpc`.
+	Flag string
+
+	index []int
+}
+
+// Override is a value of a config field, strings could be unquoted and other values are in JSON.
+type Override struct {
+	Path  string
+	Value string
+}
+
+var fields = collectFields(reflect.TypeOf(Config{}), nil, nil)
+
+// Fields returns every overridable config field, nested structs are flattened while slices are set as a whole.
+func Fields() []*Field {
+	return fields
+}
+
+func collectFields(t reflect.Type, path []string, index []int) []*Field {
+	var ret []*Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || name == "" || !f.IsExported() {
+			continue
+		}
+		p := append(append([]string(nil), path...), name)
+		idx := append(append([]int(nil), index...), i)
+		if f.Type.Kind() == reflect.Struct && !reflect.PointerTo(f.Type).Implements(jsonUnmarshaler) {
+			ret = append(ret, collectFields(f.Type, p, idx)...)
+			continue
+		}
+
+		envParts := make([]string, 0, len(p))
+		flagParts := make([]string, 0, len(p))
+		for _, s := range p {
+			words := splitWords(s)
+			envParts = append(envParts, strings.ToUpper(strings.Join(words, "_")))
+			flagParts = append(flagParts, strings.ToLower(strings.Join(words, "-")))
+		}
+		ret = append(ret, &Field{
+			Path:  strings.Join(p, "."),
+			Env:   EnvPrefix + strings.Join(envParts, "_"),
+			Flag:  strings.Join(flagParts, "."),
+			index: idx,
+		})
+	}
+	return ret
+}
+
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// splitWords splits a camel case name into words, keeping acronyms together, e.g. `bitcoinRPC` to `bitcoin` and `RPC`.
+func splitWords(s string) []string {
+	var (
+		words []string
+		start int
+	)
+	runes := []rune(s)
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsLower(next)) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
+
+// EnvOverrides returns the overrides from the environment variables. A variable with EnvFileSuffix reads the value
+// from the file it refers to, the trailing newlines are trimmed.
+func EnvOverrides(lookup func(string) (string, bool)) ([]*Override, error) {
+	var ret []*Override
+	for _, f := range fields {
+		if path, ok := lookup(f.Env + EnvFileSuffix); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read %s error: %v", f.Env+EnvFileSuffix, err)
+			}
+			ret = append(ret, &Override{Path: f.Path, Value: strings.TrimRight(string(data), "\r\n")})
+		}
+		if v, ok := lookup(f.Env); ok {
+			ret = append(ret, &Override{Path: f.Path, Value: v})
+		}
+	}
+	return ret, nil
+}
+
+// Apply sets the overrides in order, so the later ones take precedence.
+func (c *Config) Apply(overrides ...*Override) error {
+	var errs ValidationError
+	for _, o := range overrides {
+		i := fieldIndex(o.Path)
+		if i < 0 {
+			errs.add(o.Path, "unknown field")
+			continue
+		}
+		v := reflect.ValueOf(c).Elem().FieldByIndex(fields[i].index)
+		ptr := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(o.Value), ptr.Interface()); err != nil {
+			// Strings and humanized durations could be unquoted.
+			quoted, _ := json.Marshal(o.Value)
+			if err := json.Unmarshal(quoted, ptr.Interface()); err != nil {
+				errs.add(o.Path, "invalid value %q: %v", o.Value, err)
+				continue
+			}
+		}
+		v.Set(ptr.Elem())
+	}
+	return errs.err()
+}
+
+func fieldIndex(path string) int {
+	for i, f := range fields {
+		if f.Path == path {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,66 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFields(t *testing.T) {
+	for _, tt := range []struct{ path, env, flag string }{
+		{"listenAddr", "LIGHT_LISTEN_ADDR", "listen-addr"},
+		{"verification.bitcoinRPC", "LIGHT_VERIFICATION_BITCOIN_RPC", "verification.bitcoin-rpc"},
+		{"report.gasCoupon", "LIGHT_REPORT_GAS_COUPON", "report.gas-coupon"},
+		{"log.maxSizeMB", "LIGHT_LOG_MAX_SIZE_MB", "log.max-size-mb"},
+		{"committeeIndexers.s3", "LIGHT_COMMITTEE_INDEXERS_S3", "committee-indexers.s3"},
+	} {
+		i := fieldIndex(tt.path)
+		if i < 0 {
+			t.Fatalf("field %s not found", tt.path)
+		}
+		if f := Fields()[i]; f.Env != tt.env || f.Flag != tt.flag {
+			t.Fatalf("unexpected field: path=%s, env=%s, flag=%s", f.Path, f.Env, f.Flag)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	coupon := filepath.Join(t.TempDir(), "coupon")
+	if err := os.WriteFile(coupon, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"LIGHT_COMMITTEE_INDEXERS_S3":           `[{"region": "us-west-2", "bucket": "b", "name": "a"}]`,
+		"LIGHT_VERIFICATION_BITCOIN_RPC":        "https://example.com",
+		"LIGHT_REPORT_GAS_COUPON_FILE":          coupon,
+		"LIGHT_REPORT_TIMEOUT":                  "1m",
+		"LIGHT_VERIFICATION_MINIMAL_CHECKPOINT": "2",
+	}
+	overrides, err := EnvOverrides(func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The flag takes precedence over the environment variable.
+	overrides = append(overrides, &Override{Path: "verification.minimalCheckpoint", Value: "1"})
+	c, err := ParseConfig([]byte(`{"listenAddr": ":9090", "verification": {"minimalCheckpoint": 3}}`), overrides...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ListenAddr != ":9090" || c.Verification.BitcoinRPC != "https://example.com" ||
+		c.Verification.MinimalCheckpoint != 1 || len(c.CommitteeIndexers.S3) != 1 ||
+		c.Report.GasCoupon != "secret" || c.Report.Timeout.Duration != time.Minute {
+		t.Fatal("overrides not applied", c)
+	}
+
+	if _, err := ParseConfig(nil, &Override{Path: "verification.minimalCheckpoint", Value: "two"}); err == nil {
+		t.Fatal("expected invalid value error")
+	}
+	if _, err := ParseConfig(nil, &Override{Path: "verification.unknown", Value: "1"}); err == nil {
+		t.Fatal("expected unknown field error")
+	}
+}
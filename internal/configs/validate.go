@@ -0,0 +1,298 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+const (
+	DefaultListenAddr        = ":8080"
+	DefaultAdminListenAddr   = "127.0.0.1:8081"
+	DefaultMetaProtocol      = "brc-20"
+	DefaultMinimalCheckpoint = 1
+	DefaultReportTimeout     = 15 * time.Second
+	DefaultReportJitter      = 40 * time.Second
+	DefaultReportMaxBackoff  = 10 * time.Minute
+)
+
+// FieldError is a validation error of a config field, the field is the JSON path like `committeeIndexers.s3[0].bucket`.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError is all field errors of a config.
+type ValidationError []*FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return fmt.Sprintf("invalid config:\n  %s", strings.Join(msgs, "\n  "))
+}
+
+func (e *ValidationError) add(field, format string, a ...any) {
+	*e = append(*e, &FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+func (e ValidationError) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ParseConfig decodes the config rejecting unknown fields, applies the overrides, fills the defaults and validates it.
+// Empty data means a config of the overrides only.
+func ParseConfig(data []byte, overrides ...*Override) (*Config, error) {
+	var c Config
+	if len(bytes.TrimSpace(data)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil {
+			return nil, fmt.Errorf("invalid config: %v", err)
+		}
+	}
+	if err := c.Apply(overrides...); err != nil {
+		return nil, err
+	}
+	c.SetDefaults()
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// SetDefaults fills the zero fields having defaults.
+func (c *Config) SetDefaults() {
+	if c.ListenAddr == "" {
+		c.ListenAddr = DefaultListenAddr
+	}
+	if c.Admin.ListenAddr == "" {
+		c.Admin.ListenAddr = DefaultAdminListenAddr
+	}
+	if c.Verification.MetaProtocol == "" {
+		c.Verification.MetaProtocol = DefaultMetaProtocol
+	}
+	if c.Verification.MinimalCheckpoint == 0 {
+		c.Verification.MinimalCheckpoint = DefaultMinimalCheckpoint
+	}
+	if c.Report.Timeout.Duration == 0 {
+		c.Report.Timeout.Duration = DefaultReportTimeout
+	}
+	if c.Report.Jitter.Duration == 0 {
+		c.Report.Jitter.Duration = DefaultReportJitter
+	}
+	if c.Report.MaxBackoff.Duration == 0 {
+		c.Report.MaxBackoff.Duration = DefaultReportMaxBackoff
+	}
+}
+
+// Validate checks every field and returns a ValidationError of all invalid ones.
+func (c *Config) Validate() error {
+	var errs ValidationError
+
+	_, port, err := net.SplitHostPort(c.ListenAddr)
+	if err != nil {
+		errs.add("listenAddr", "invalid address %q: %v", c.ListenAddr, err)
+	}
+	if host, adminPort, err := net.SplitHostPort(c.Admin.ListenAddr); err != nil {
+		errs.add("admin.listenAddr", "invalid address %q: %v", c.Admin.ListenAddr, err)
+	} else {
+		if adminPort == port && port != "0" {
+			errs.add("admin.listenAddr", "should not share the port of listenAddr %q", c.ListenAddr)
+		}
+		if c.Admin.Token == "" && !isLoopback(host) {
+			errs.add("admin.token", "required since admin.listenAddr %q isn't on the loopback interface", c.Admin.ListenAddr)
+		}
+	}
+	if _, err := c.Admin.WebhookAllowPrefixes(); err != nil {
+		errs.add("admin.webhookAllowList", "%v", err)
+	}
+
+	indexers := &c.CommitteeIndexers
+	names := make(map[string]string)
+	checkName := func(field, name string) {
+		if name == "" {
+			errs.add(field, "required")
+			return
+		}
+		if dup, ok := names[name]; ok {
+			errs.add(field, "duplicate name %q with %s", name, dup)
+			return
+		}
+		names[name] = field
+	}
+	for i, s := range indexers.S3 {
+		field := fmt.Sprintf("committeeIndexers.s3[%d]", i)
+		if s.Region == "" {
+			errs.add(field+".region", "required")
+		}
+		if s.Bucket == "" {
+			errs.add(field+".bucket", "required")
+		}
+		checkName(field+".name", s.Name)
+	}
+	for i, s := range indexers.DA {
+		field := fmt.Sprintf("committeeIndexers.da[%d]", i)
+		if s.Network == "" {
+			errs.add(field+".network", "required")
+		}
+		if !checkpoint.IsValidNamespaceID(s.NamespaceID) {
+			errs.add(field+".namespaceID", "invalid namespace ID %q", s.NamespaceID)
+		}
+		checkName(field+".name", s.Name)
+	}
+	providers := len(indexers.S3) + len(indexers.DA)
+	if providers == 0 && len(indexers.Raw) == 0 {
+		errs.add("committeeIndexers", "at least one S3 or DA committee indexer is required")
+	}
+
+	v := &c.Verification
+	if u, err := url.Parse(v.BitcoinRPC); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.add("verification.bitcoinRPC", "invalid HTTP(S) URL %q", v.BitcoinRPC)
+	}
+	if v.MinimalCheckpoint < 1 {
+		errs.add("verification.minimalCheckpoint", "should be at least 1, got %d", v.MinimalCheckpoint)
+	} else if providers > 0 && v.MinimalCheckpoint > providers {
+		errs.add("verification.minimalCheckpoint", "%d exceeds the number of committee indexers %d", v.MinimalCheckpoint, providers)
+	}
+	if v.HistorySize < 0 {
+		errs.add("verification.historySize", "should not be negative, got %d", v.HistorySize)
+	}
+	if v.DenyExpiry.Duration < 0 {
+		errs.add("verification.denyExpiry", "should not be negative, got %s", v.DenyExpiry.Duration)
+	}
+	for i, k := range v.Signers {
+		if data, err := hex.DecodeString(k); err != nil || len(data) != schnorr.PubKeyBytesLen {
+			errs.add(fmt.Sprintf("verification.signers[%d]", i), "invalid x-only public key %q", k)
+		} else if _, err := schnorr.ParsePubKey(data); err != nil {
+			errs.add(fmt.Sprintf("verification.signers[%d]", i), "invalid x-only public key %q: %v", k, err)
+		}
+	}
+
+	if c.Report.Timeout.Duration < 0 {
+		errs.add("report.timeout", "should not be negative, got %s", c.Report.Timeout.Duration)
+	}
+	if c.Report.Jitter.Duration < 0 {
+		errs.add("report.jitter", "should not be negative, got %s", c.Report.Jitter.Duration)
+	}
+	if c.Report.MaxBackoff.Duration < 0 {
+		errs.add("report.maxBackoff", "should not be negative, got %s", c.Report.MaxBackoff.Duration)
+	}
+
+	for i, s := range c.Sinks.S3 {
+		field := fmt.Sprintf("sinks.s3[%d]", i)
+		if s.Region == "" {
+			errs.add(field+".region", "required")
+		}
+		if s.Bucket == "" {
+			errs.add(field+".bucket", "required")
+		}
+		if s.Endpoint != "" {
+			if u, err := url.Parse(s.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs.add(field+".endpoint", "invalid HTTP(S) URL %q", s.Endpoint)
+			}
+		}
+		if (s.AccessKeyID == "") != (s.SecretAccessKey == "") {
+			errs.add(field+".secretAccessKey", "required with accessKeyID, or neither")
+		}
+	}
+	for i, w := range c.Sinks.Webhook {
+		if u, err := url.Parse(w); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add(fmt.Sprintf("sinks.webhook[%d]", i), "invalid HTTP(S) URL %q", w)
+		}
+	}
+	for i, f := range c.Sinks.File {
+		if f == "" {
+			errs.add(fmt.Sprintf("sinks.file[%d]", i), "required")
+		}
+	}
+	if c.Sinks.Enabled() && c.Report.Name == "" {
+		errs.add("report.name", "required by sinks")
+	}
+
+	if c.Fraud.NamespaceID != "" && !checkpoint.IsValidNamespaceID(c.Fraud.NamespaceID) {
+		errs.add("fraud.namespaceID", "invalid namespace ID %q", c.Fraud.NamespaceID)
+	}
+	if c.Fraud.Webhook != "" {
+		if u, err := url.Parse(c.Fraud.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("fraud.webhook", "invalid HTTP(S) URL %q", c.Fraud.Webhook)
+		}
+	}
+	for i, nid := range c.Fraud.Import {
+		if !checkpoint.IsValidNamespaceID(nid) {
+			errs.add(fmt.Sprintf("fraud.import[%d]", i), "invalid namespace ID %q", nid)
+		}
+	}
+
+	if err := c.Log.Validate(); err != nil {
+		errs.add("log", "%v", err)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		errs.add("tracing", "%v", err)
+	}
+	if c.Health.MaxSyncInterval.Duration < 0 {
+		errs.add("health.maxSyncInterval", "should not be negative, got %s", c.Health.MaxSyncInterval.Duration)
+	}
+
+	return errs.err()
+}
+
+// WebhookAllowPrefixes parses the webhook allow list, an IP is the prefix of its full length.
+func (a *Admin) WebhookAllowPrefixes() ([]netip.Prefix, error) {
+	ret := make([]netip.Prefix, 0, len(a.WebhookAllowList))
+	for _, s := range a.WebhookAllowList {
+		if addr, err := netip.ParseAddr(s); err == nil {
+			ret = append(ret, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", s)
+		}
+		ret = append(ret, p.Masked())
+	}
+	return ret, nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Validate checks the fields required to upload checkpoints to DA, the namespace ID could be empty to create one.
+func (r *Report) Validate() error {
+	var errs ValidationError
+	if r.Name == "" {
+		errs.add("report.name", "required")
+	}
+	if r.Network == "" {
+		errs.add("report.network", "required")
+	}
+	if r.GasCoupon == "" {
+		errs.add("report.gasCoupon", "required")
+	}
+	if r.Timeout.Duration <= 0 {
+		errs.add("report.timeout", "should be positive, got %s", r.Timeout.Duration)
+	}
+	return errs.err()
+}
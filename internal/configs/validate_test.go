@@ -0,0 +1,62 @@
+package configs
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	data, err := os.ReadFile("../../config.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Report.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseConfig([]byte(`{"verificaton": {}}`)); err == nil {
+		t.Fatal("expected unknown field error")
+	}
+
+	c, err = ParseConfig([]byte(`{
+		"committeeIndexers": {"s3": [{"region": "us-west-2", "name": "a"}]},
+		"verification": {"bitcoinRPC": "https://example.com"}
+	}`))
+	var verr ValidationError
+	if !errors.As(err, &verr) || len(verr) != 1 || verr[0].Field != "committeeIndexers.s3[0].bucket" {
+		t.Fatal(c, err)
+	}
+
+	c, err = ParseConfig([]byte(`{
+		"committeeIndexers": {"s3": [{"region": "us-west-2", "bucket": "b", "name": "a"}]},
+		"verification": {"bitcoinRPC": "https://example.com"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ListenAddr != DefaultListenAddr || c.Admin.ListenAddr != DefaultAdminListenAddr || c.Verification.MetaProtocol != DefaultMetaProtocol ||
+		c.Verification.MinimalCheckpoint != DefaultMinimalCheckpoint || c.Report.Timeout.Duration != DefaultReportTimeout {
+		t.Fatal("defaults not set", c)
+	}
+
+	for _, admin := range []string{
+		`{"listenAddr": ":8081"}`,
+		`{"listenAddr": "127.0.0.1:8080"}`,
+		`{"webhookAllowList": ["10.0.0.0/8", "localhost"]}`,
+	} {
+		c, err = ParseConfig([]byte(`{
+			"admin": ` + admin + `,
+			"committeeIndexers": {"s3": [{"region": "us-west-2", "bucket": "b", "name": "a"}]},
+			"verification": {"bitcoinRPC": "https://example.com"}
+		}`))
+		if !errors.As(err, &verr) || len(verr) != 1 || !strings.HasPrefix(verr[0].Field, "admin.") {
+			t.Fatal(admin, c, err)
+		}
+	}
+}
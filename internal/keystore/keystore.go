@@ -0,0 +1,226 @@
+// Package keystore stores private keys encrypted by a passphrase, with scrypt to derive the key and AES-256-GCM to
+// encrypt.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	Version = 1
+
+	KDFScrypt    = "scrypt"
+	CipherAESGCM = "aes-256-gcm"
+
+	// DefaultScryptN, DefaultScryptR and DefaultScryptP are the scrypt parameters of the new keystores, taking about
+	// 256 MiB of memory.
+	DefaultScryptN = 1 << 18
+	DefaultScryptR = 8
+	DefaultScryptP = 1
+
+	keyLen  = 32
+	saltLen = 32
+)
+
+// ErrPassphrase is the error of decrypting with a wrong passphrase.
+var ErrPassphrase = errors.New("wrong passphrase or corrupted keystore")
+
+// scryptN is the scrypt cost of the new keystores, lowered by tests.
+var scryptN = DefaultScryptN
+
+// Keystore is an encrypted private key, with the optional backup of the wallet it's derived from.
+type Keystore struct {
+	Version int `json:"version"`
+
+	// Address and PublicKey of the key, readable without the passphrase.
+	Address   string `json:"address,omitempty"`
+	PublicKey string `json:"publicKey,omitempty"`
+
+	KDF       string       `json:"kdf"`
+	KDFParams ScryptParams `json:"kdfParams"`
+
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+
+	// WalletNonce and WalletCiphertext are the encrypted wallet, empty if the key isn't derived from a wallet.
+	WalletNonce      string `json:"walletNonce,omitempty"`
+	WalletCiphertext string `json:"walletCiphertext,omitempty"`
+}
+
+type ScryptParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+// Encrypt encrypts the private key and the wallet, nil if none, by the passphrase with a random salt and nonces.
+func Encrypt(key, wallet, passphrase []byte) (*Keystore, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	ks := &Keystore{
+		Version:   Version,
+		KDF:       KDFScrypt,
+		KDFParams: ScryptParams{N: scryptN, R: DefaultScryptR, P: DefaultScryptP, Salt: hex.EncodeToString(salt)},
+		Cipher:    CipherAESGCM,
+	}
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if ks.Nonce, ks.Ciphertext, err = seal(aead, key); err != nil {
+		return nil, err
+	}
+	if wallet != nil {
+		if ks.WalletNonce, ks.WalletCiphertext, err = seal(aead, wallet); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Decrypt returns the private key and the wallet, nil if none, or ErrPassphrase if the passphrase is wrong.
+func (ks *Keystore) Decrypt(passphrase []byte) (key, wallet []byte, err error) {
+	if ks.Version != Version {
+		return nil, nil, fmt.Errorf("unsupported keystore version: %d", ks.Version)
+	}
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	if key, err = open(aead, ks.Nonce, ks.Ciphertext); err != nil {
+		return nil, nil, err
+	}
+	if ks.WalletCiphertext != "" {
+		if wallet, err = open(aead, ks.WalletNonce, ks.WalletCiphertext); err != nil {
+			return nil, nil, err
+		}
+	}
+	return key, wallet, nil
+}
+
+func seal(aead cipher.AEAD, data []byte) (nonce, ciphertext string, err error) {
+	n := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(n); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(n), hex.EncodeToString(aead.Seal(nil, n, data, nil)), nil
+}
+
+func open(aead cipher.AEAD, nonce, ciphertext string) ([]byte, error) {
+	n, err := hex.DecodeString(nonce)
+	if err != nil || len(n) != aead.NonceSize() {
+		return nil, errors.New("invalid keystore nonce")
+	}
+	c, err := hex.DecodeString(ciphertext)
+	if err != nil {
+		return nil, errors.New("invalid keystore ciphertext")
+	}
+	data, err := aead.Open(nil, n, c, nil)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return data, nil
+}
+
+func (ks *Keystore) aead(passphrase []byte) (cipher.AEAD, error) {
+	if ks.KDF != KDFScrypt {
+		return nil, fmt.Errorf("unsupported keystore KDF: %s", ks.KDF)
+	}
+	if ks.Cipher != CipherAESGCM {
+		return nil, fmt.Errorf("unsupported keystore cipher: %s", ks.Cipher)
+	}
+	p := &ks.KDFParams
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("invalid keystore salt")
+	}
+	dk, err := scrypt.Key(passphrase, salt, p.N, p.R, p.P, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore scrypt params: n=%d, r=%d, p=%d, err=%v", p.N, p.R, p.P, err)
+	}
+	block, err := aes.NewCipher(dk)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsPlaintext reports whether the file content is a private key in hex, as written by the previous versions.
+func IsPlaintext(data []byte) bool {
+	s := strings.TrimPrefix(strings.TrimSpace(string(data)), "0x")
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == keyLen
+}
+
+// Parse parses the keystore file content.
+func Parse(data []byte) (*Keystore, error) {
+	var ks Keystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("invalid keystore: %v", err)
+	}
+	return &ks, nil
+}
+
+// Read reads the keystore file, and tightens its permissions to owner-only if they're looser.
+func Read(path string) (*Keystore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := tighten(path); err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Write writes the keystore to a temporary file then renames it with owner-only permissions, so a crash never leaves a
+// partial file.
+func Write(path string, ks *Keystore) error {
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := tmp.Chmod(0600); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func tighten(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 == 0 {
+		return nil
+	}
+	return os.Chmod(path, 0600)
+}
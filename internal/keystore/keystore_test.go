@@ -0,0 +1,72 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestKeystore(t *testing.T) {
+	scryptN = 1 << 10
+	defer func() { scryptN = DefaultScryptN }()
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	ks, err := Encrypt(key, []byte("wallet"), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Address = "address"
+
+	path := filepath.Join(t.TempDir(), "private")
+	if err := Write(path, ks); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if perm := info.Mode().Perm(); runtime.GOOS != "windows" && perm != 0600 {
+		t.Fatalf("unexpected permissions: %o", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsPlaintext(data) || bytes.Contains(data, []byte("4242")) {
+		t.Fatal("expected the key encrypted")
+	}
+
+	if ks, err = Read(path); err != nil {
+		t.Fatal(err)
+	}
+	if ks.Address != "address" {
+		t.Fatal("unexpected address", ks.Address)
+	}
+	if _, _, err := ks.Decrypt([]byte("wrong")); !errors.Is(err, ErrPassphrase) {
+		t.Fatal("expected wrong passphrase", err)
+	}
+	got, wallet, err := ks.Decrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, key) || string(wallet) != "wallet" {
+		t.Fatal("unexpected key or wallet")
+	}
+}
+
+func TestIsPlaintext(t *testing.T) {
+	for _, c := range []struct {
+		data     string
+		expected bool
+	}{
+		{"4242424242424242424242424242424242424242424242424242424242424242", true},
+		{"0x4242424242424242424242424242424242424242424242424242424242424242\n", true},
+		{"4242", false},
+		{`{"version":1}`, false},
+	} {
+		if actual := IsPlaintext([]byte(c.data)); actual != c.expected {
+			t.Fatalf("unexpected result: data=%q, expected=%t, actual=%t", c.data, c.expected, actual)
+		}
+	}
+}
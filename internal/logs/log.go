@@ -1,16 +1,203 @@
 package logs
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/httputl"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
-func newLogger(prefix string) *log.Logger {
-	return log.New(os.Stdout, prefix, log.LstdFlags|log.Lmicroseconds|log.Lshortfile|log.Lmsgprefix)
+// Options of the log output, zero values mean text logs of info level to stdout.
+type Options struct {
+	Level  string `json:"level,omitempty"`
+	Format string `json:"format,omitempty"`
+
+	// File enables the file output with rotation besides stdout.
+	File       string `json:"file,omitempty"`
+	MaxSizeMB  int    `json:"maxSizeMB,omitempty"`
+	MaxBackups int    `json:"maxBackups,omitempty"`
+	MaxAgeDays int    `json:"maxAgeDays,omitempty"`
 }
 
 var (
-	Info  = newLogger("INFO ")
-	Warn  = newLogger("WARN ")
-	Error = newLogger("ERROR ")
+	level   = new(slog.LevelVar)
+	handler atomic.Pointer[slog.Handler]
 )
+
+func init() {
+	var h slog.Handler = slog.NewTextHandler(os.Stdout, handlerOptions())
+	handler.Store(&h)
+}
+
+func handlerOptions() *slog.HandlerOptions {
+	return &slog.HandlerOptions{AddSource: true, Level: level}
+}
+
+// Validate checks the level and the format of the options.
+func (o *Options) Validate() error {
+	if _, err := parseLevel(o.Level); err != nil {
+		return err
+	}
+	switch o.Format {
+	case "", FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format: %q", o.Format)
+	}
+	if o.MaxSizeMB < 0 || o.MaxBackups < 0 || o.MaxAgeDays < 0 {
+		return errors.New("negative rotation limits")
+	}
+	return nil
+}
+
+// Init replaces the log output according to the options.
+func Init(o *Options) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if err := SetLevel(o.Level); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if o.File != "" {
+		w = io.MultiWriter(os.Stdout, &lumberjack.Logger{
+			Filename:   o.File,
+			MaxSize:    o.MaxSizeMB,
+			MaxBackups: o.MaxBackups,
+			MaxAge:     o.MaxAgeDays,
+		})
+	}
+
+	var h slog.Handler
+	switch o.Format {
+	case "", FormatText:
+		h = slog.NewTextHandler(w, handlerOptions())
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, handlerOptions())
+	default:
+		return fmt.Errorf("unknown log format: %q", o.Format)
+	}
+	handler.Store(&h)
+	return nil
+}
+
+// SetLevel changes the minimal level of logs at runtime, empty level means info.
+func SetLevel(s string) error {
+	l, err := parseLevel(s)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToUpper(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level: %q", s)
+	}
+	return l, nil
+}
+
+// Level returns the current minimal level of logs.
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+// Logger is a leveled logger with contextual fields, the printing methods mirror the standard log.Logger.
+type Logger struct {
+	level slog.Level
+	attrs []slog.Attr
+}
+
+var (
+	Debug = &Logger{level: slog.LevelDebug}
+	Info  = &Logger{level: slog.LevelInfo}
+	Warn  = &Logger{level: slog.LevelWarn}
+	Error = &Logger{level: slog.LevelError}
+)
+
+// With returns a logger with the key-value pairs attached to every record.
+func (l *Logger) With(args ...any) *Logger {
+	r := slog.NewRecord(time.Time{}, l.level, "", 0)
+	r.Add(args...)
+	attrs := append([]slog.Attr(nil), l.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return &Logger{level: l.level, attrs: attrs}
+}
+
+// Ctx returns a logger with the request ID of the context attached.
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	if reqID := httputl.RequestID(ctx); reqID != "" {
+		return l.With("reqID", reqID)
+	}
+	return l
+}
+
+func (l *Logger) log(msg string) {
+	h := *handler.Load()
+	if !h.Enabled(context.Background(), l.level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // Skip runtime.Callers, log and the printing method.
+	r := slog.NewRecord(time.Now(), l.level, msg, pcs[0])
+	r.AddAttrs(l.attrs...)
+	_ = h.Handle(context.Background(), r)
+}
+
+func (l *Logger) Print(v ...any) { l.log(fmt.Sprint(v...)) }
+
+func (l *Logger) Printf(format string, v ...any) { l.log(fmt.Sprintf(format, v...)) }
+
+func (l *Logger) Println(v ...any) { l.log(strings.TrimSuffix(fmt.Sprintln(v...), "\n")) }
+
+func (l *Logger) Fatal(v ...any) {
+	l.log(fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+func (l *Logger) Fatalf(format string, v ...any) {
+	l.log(fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+func (l *Logger) Fatalln(v ...any) {
+	l.log(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	os.Exit(1)
+}
+
+// Writer returns a writer logging every line, for libraries writing to an io.Writer.
+func (l *Logger) Writer() io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+			l.log(line)
+		}
+		return len(p), nil
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
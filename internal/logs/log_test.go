@@ -0,0 +1,45 @@
+package logs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/httputl"
+)
+
+func TestLogger(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "light.log")
+	if err := Init(&Options{Level: "warn", Format: FormatJSON, File: file}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = Init(&Options{}) }()
+
+	Info.Println("dropped")
+	Warn.Ctx(httputl.WithRequestID(httputl.TODO(), "req-1")).With("height", 840000).Printf("hello %s", "world")
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatal(lines)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["msg"] != "hello world" || record["level"] != "WARN" || record["reqID"] != "req-1" || record["height"] != float64(840000) {
+		t.Fatal(record)
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := SetLevel("debug"); err != nil || Level() != "debug" {
+		t.Fatal(Level(), err)
+	}
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "light_indexer"
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Default is the verification progress of the daemon, in the default registry.
+var Default = MustVerification(prometheus.DefaultRegisterer)
+
+var (
+	ProviderFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_fetch_duration_seconds",
+		Help:      "Latency of fetching a checkpoint from a provider.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"provider", "result"})
+
+	ProviderFetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_fetch_errors_total",
+		Help:      "Number of failed checkpoint fetches by provider.",
+	}, []string{"provider"})
+
+	ConflictsDetected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "conflicts_detected_total",
+		Help:      "Number of heights where providers reported inconsistent checkpoints.",
+	})
+
+	DenyListAdditions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deny_list_additions_total",
+		Help:      "Number of providers added to the deny list.",
+	})
+
+	RPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of JSON-RPC calls by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "result"})
+
+	BalanceVerifyFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "balance_verify_failures_total",
+		Help:      "Number of balance proofs failed to verify by kind.",
+	}, []string{"kind"})
+
+	DAUploads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "da_uploads_total",
+		Help:      "Number of checkpoint uploads to DA by result.",
+	}, []string{"result"})
+
+	CheckpointReports = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checkpoint_reports_total",
+		Help:      "Number of checkpoint reports by reporter and result.",
+	}, []string{"reporter", "result"})
+
+	ReportQueueSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "report_queue_size",
+		Help:      "Number of checkpoint reports waiting in the queue.",
+	})
+
+	FraudPublishes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fraud_publishes_total",
+		Help:      "Number of fraud reports published by target and result.",
+	}, []string{"target", "result"})
+
+	FraudImports = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fraud_imports_total",
+		Help:      "Number of fraud reports imported by result.",
+	}, []string{"result"})
+)
+
+// Verification is the gauges of the verification progress of one state. Every state owns one, so the states embedded
+// in one process don't overwrite each other's progress.
+type Verification struct {
+	status   prometheus.Gauge
+	tip      atomic.Uint64
+	verified atomic.Uint64
+}
+
+// NewVerification creates the verification gauges and registers them to the registerer, nil leaves them unregistered.
+func NewVerification(reg prometheus.Registerer) (*Verification, error) {
+	v := &Verification{
+		status: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "verification_status",
+			Help:      "Current verification status: 1 for verified, 2 for verifying and 3 for unverified.",
+		}),
+	}
+	if reg == nil {
+		return v, nil
+	}
+	collectors := []prometheus.Collector{
+		v.status,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "verified_height",
+			Help:      "Block height of the latest verified checkpoint.",
+		}, func() float64 { return float64(v.verified.Load()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "bitcoin_tip_height",
+			Help:      "Latest block height reported by the Bitcoin RPC.",
+		}, func() float64 { return float64(v.tip.Load()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "verified_lag_blocks",
+			Help:      "Number of blocks the latest verified checkpoint is behind the Bitcoin tip.",
+		}, func() float64 {
+			tip, verified := v.tip.Load(), v.verified.Load()
+			if tip == 0 || verified == 0 {
+				return math.NaN()
+			}
+			return float64(tip) - float64(verified)
+		}),
+	}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// MustVerification is like NewVerification but panics if the gauges can't be registered.
+func MustVerification(reg prometheus.Registerer) *Verification {
+	v, err := NewVerification(reg)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v *Verification) SetStatus(status int) { v.status.Set(float64(status)) }
+
+func (v *Verification) SetTipHeight(height uint) { v.tip.Store(uint64(height)) }
+
+func (v *Verification) SetVerifiedHeight(height uint) { v.verified.Store(uint64(height)) }
+
+// Result returns the result label of an error.
+func Result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+// Since observes the seconds elapsed since start.
+func Since(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,156 @@
+package reports
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	sdk "github.com/RiemaLabs/nubit-da-sdk"
+	"github.com/RiemaLabs/nubit-da-sdk/constant"
+	"github.com/RiemaLabs/nubit-da-sdk/types"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/utils"
+)
+
+const (
+	// DefaultReadLimit is the number of data read from a DA namespace per request.
+	DefaultReadLimit = 100
+
+	// DefaultNamespacePollInterval is the interval of polling the transaction creating a namespace until it's in a block.
+	DefaultNamespacePollInterval = 5 * time.Second
+)
+
+// nubit returns the DA client of the network, the private key is only required for uploads.
+func nubit(ctx context.Context, network, privateKey, gasCoupon string) (*sdk.NubitSDK, error) {
+	switch network {
+	case "Pre-Alpha Testnet":
+		sdk.SetNet(constant.PreAlphaTestNet)
+	case "Testnet":
+		sdk.SetNet(constant.TestNet)
+	default:
+		return nil, fmt.Errorf("unknown network: %s", network)
+	}
+	cl := sdk.NewNubit(sdk.WithCtx(ctx), sdk.WithGasCode(gasCoupon), sdk.WithPrivateKey(privateKey))
+	if cl == nil || cl.Client == nil {
+		return nil, fmt.Errorf("failed to build the Nubit client: network=%s", network)
+	}
+	return cl, nil
+}
+
+// UploadDA uploads the JSON data to the namespace, the same way as checkpoint.UploadCheckpointByDA.
+func UploadDA(ctx context.Context, data []byte, privateKey, gasCoupon, namespaceID, network string) error {
+	cl, err := nubit(ctx, network, privateKey, gasCoupon)
+	if err != nil {
+		return err
+	}
+	labels := map[string]any{"contentType": "application/json"}
+	if _, err := cl.UploadBytes(data, namespaceID, 0, labels); err != nil {
+		return fmt.Errorf("failed to upload to DA: namespaceID=%s, err=%v", namespaceID, err)
+	}
+	return nil
+}
+
+// ReadDA reads the data of the namespace from the offset, and returns the offset to read next. Data failing to read
+// are skipped.
+func ReadDA(ctx context.Context, network, namespaceID string, offset int) ([][]byte, int, error) {
+	cl, err := nubit(ctx, network, "", "")
+	if err != nil {
+		return nil, offset, err
+	}
+	var ret [][]byte
+	for {
+		rsp, err := cl.Client.GetDataInNamespace(ctx, &types.GetDataInNamespaceReq{
+			NID:    namespaceID,
+			Limit:  DefaultReadLimit,
+			Offset: offset,
+		})
+		if err != nil {
+			return ret, offset, fmt.Errorf("failed to list DA namespace: namespaceID=%s, offset=%d, err=%v", namespaceID, offset, err)
+		}
+		if len(rsp.DataIDs) == 0 {
+			return ret, offset, nil
+		}
+		for _, id := range rsp.DataIDs {
+			d, err := cl.Client.GetData(ctx, &types.GetDataReq{DAID: id})
+			if err == nil && d == nil {
+				err = errors.New("not found")
+			}
+			if err != nil {
+				logs.Warn.With("namespaceID", namespaceID, "dataID", id).Printf("Failed to read DA data: %v", err)
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(d.RawData)
+			if err != nil {
+				logs.Warn.With("namespaceID", namespaceID, "dataID", id).Printf("Invalid DA data: %v", err)
+				continue
+			}
+			ret = append(ret, data)
+		}
+		if next := int(rsp.LastOffset); next > offset {
+			offset = next
+		} else {
+			offset += len(rsp.DataIDs)
+		}
+	}
+}
+
+// FindNamespace returns the ID of the namespace of the name owned by the private key, empty if there is none.
+func FindNamespace(ctx context.Context, network, privateKey, name string) (string, error) {
+	cl, err := nubit(ctx, network, "", "")
+	if err != nil {
+		return "", err
+	}
+	req := &types.GetNamespacesReq{Limit: DefaultReadLimit}
+	req.Filter.Owner = utils.PrivateStrToBtcAddress(privateKey)
+	if req.Filter.Owner == "" {
+		return "", errors.New("invalid private key")
+	}
+	for {
+		rsp, err := cl.Client.GetNamespaces(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to list DA namespaces: owner=%s, err=%v", req.Filter.Owner, err)
+		}
+		for _, ns := range rsp.Namespaces {
+			if ns.Name == name {
+				return ns.NamespaceID, nil
+			}
+		}
+		if len(rsp.Namespaces) == 0 || rsp.LastOffset <= req.Offset {
+			return "", nil
+		}
+		req.Offset = rsp.LastOffset
+	}
+}
+
+// CreateNamespace returns the ID of the namespace of the name owned by the private key, which is created if there is
+// none, so it's safe to retry. It waits until the namespace is in a block, or the context is done.
+func CreateNamespace(ctx context.Context, network, privateKey, gasCoupon, name string) (nid string, created bool, err error) {
+	if nid, err := FindNamespace(ctx, network, privateKey, name); err != nil || nid != "" {
+		return nid, false, err
+	}
+
+	cl, err := nubit(ctx, network, privateKey, gasCoupon)
+	if err != nil {
+		return "", false, err
+	}
+	ns, err := cl.CreateNamespace(name, "Private", "", nil)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create DA namespace: name=%s, err=%v", name, err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return "", false, fmt.Errorf("namespace creation unconfirmed: name=%s, txID=%s, err=%v", name, ns.TxID, ctx.Err())
+		case <-time.After(DefaultNamespacePollInterval):
+		}
+		tx, err := cl.Client.GetTransaction(ctx, &types.GetTransactionReq{TxID: ns.TxID})
+		if err != nil || tx == nil || tx.NID == "" {
+			logs.Debug.With("name", name, "txID", ns.TxID).Printf("Waiting for the namespace creation: err=%v", err)
+			continue
+		}
+		return tx.NID, true, nil
+	}
+}
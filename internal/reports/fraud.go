@@ -0,0 +1,335 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/httputl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+// FraudReportType tells the fraud reports apart from the other data in a DA namespace.
+const FraudReportType = "fraud"
+
+// DefaultPublishRetries is the number of attempts to publish a fraud report to each target.
+const DefaultPublishRetries = 3
+
+// FraudReport is a deny list entry with its fraud proof published to DA or webhooks, for other Light Indexers to import
+// after verifying it.
+type FraudReport struct {
+	Type         string            `json:"type"`
+	Reporter     string            `json:"reporter"`
+	MetaProtocol string            `json:"metaProtocol"`
+	Version      string            `json:"version"`
+	Entry        *configs.DenyList `json:"entry"`
+}
+
+func NewFraudReport(reporter, metaProtocol, version string, entry *configs.DenyList) *FraudReport {
+	return &FraudReport{
+		Type:         FraudReportType,
+		Reporter:     reporter,
+		MetaProtocol: metaProtocol,
+		Version:      version,
+		Entry:        entry,
+	}
+}
+
+// ParseFraudReports parses the fraud reports in JSON one after another, skipping other JSON objects such as checkpoints.
+func ParseFraudReports(r io.Reader) ([]*FraudReport, error) {
+	var ret []*FraudReport
+	dec := json.NewDecoder(r)
+	for {
+		var report FraudReport
+		if err := dec.Decode(&report); err != nil {
+			if errors.Is(err, io.EOF) {
+				return ret, nil
+			}
+			return nil, err
+		}
+		if report.Type == FraudReportType {
+			ret = append(ret, &report)
+		}
+	}
+}
+
+// FraudPublisher publishes the fraud reports to the DA namespace and the webhook of the config.
+type FraudPublisher struct {
+	Config *configs.Fraud
+
+	// Report is the DA network and key to upload the fraud reports, nil to publish to the webhook only.
+	Report *configs.Report
+
+	// BTC re-verifies the fraud proofs before publishing them, nil leaves the invalid transfers unconfirmed.
+	BTC *btcutl.Client
+}
+
+// Enabled reports whether there is any target to publish to.
+func (p *FraudPublisher) Enabled() bool {
+	return (p.Config.NamespaceID != "" && p.Report != nil) || p.Config.Webhook != ""
+}
+
+// Publish publishes the fraud report to every target, retrying each of them.
+func (p *FraudPublisher) Publish(ctx context.Context, r *FraudReport) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshal fraud report error: %v", err)
+	}
+
+	var errs []error
+	if nid := p.Config.NamespaceID; nid != "" && p.Report != nil {
+		err := retry(ctx, func() error {
+			uploadCtx, cancel := context.WithTimeout(ctx, p.Report.Timeout.Duration)
+			defer cancel()
+			return UploadDA(uploadCtx, data, p.Report.PrivateKey, p.Report.GasCoupon, nid, p.Report.Network)
+		})
+		metrics.FraudPublishes.WithLabelValues("da", metrics.Result(err)).Inc()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if webhook := p.Config.Webhook; webhook != "" {
+		u, err := url.Parse(webhook)
+		if err == nil {
+			err = retry(ctx, func() error { return httputl.PostJSON(ctx, u, r, nil) })
+		}
+		metrics.FraudPublishes.WithLabelValues("webhook", metrics.Result(err)).Inc()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to post to webhook: webhook=%s, err=%v", webhook, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkPublishable returns why the deny list entry isn't published, nil if its fraud proof is confirmed. The entries
+// without fraud proofs, e.g. of the checkpoints failing the verification otherwise, and the fraud proofs that couldn't
+// be confirmed, e.g. on a Bitcoin RPC failure, are no evidence for the others.
+func (p *FraudPublisher) checkPublishable(ctx context.Context, entry *configs.DenyList) error {
+	if entry.Evidence == nil || entry.Evidence.Proof == nil {
+		return errors.New("no fraud proof")
+	}
+	if r := checkpoints.VerifyFraudProof(ctx, p.BTC, entry.Evidence.Proof); r.Verdict != checkpoints.VerdictConfirmed {
+		return fmt.Errorf("fraud proof %s", r.Verdict)
+	}
+	return nil
+}
+
+func retry(ctx context.Context, f func() error) (err error) {
+	for i := 0; i < DefaultPublishRetries; i++ {
+		if err = f(); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(i+1) * time.Second):
+		}
+	}
+	return err
+}
+
+// RunFraudPublisher publishes the fraud report of every denial of the state with a confirmed fraud proof, until the
+// context is done or the state events are closed.
+func RunFraudPublisher(ctx context.Context, p *FraudPublisher, reporter, metaProtocol, version string) {
+	events, unsubscribe := states.S.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type != states.EventDenial || e.Entry == nil {
+				continue
+			}
+			fields := []any{"name", e.Entry.Name(), "height", e.Height}
+			if err := p.checkPublishable(ctx, e.Entry); err != nil {
+				logs.Info.With(fields...).Printf("Fraud report not published: %v", err)
+				continue
+			}
+			if err := p.Publish(ctx, NewFraudReport(reporter, metaProtocol, version, e.Entry)); err != nil {
+				logs.Error.With(fields...).Printf("Failed to publish fraud report: %v", err)
+				continue
+			}
+			logs.Info.With(fields...).Print("Fraud report published")
+		}
+	}
+}
+
+// Results of importing a fraud report.
+const (
+	ImportImported     = "imported"
+	ImportDuplicate    = "duplicate"
+	ImportUnknown      = "unknown"
+	ImportInvalid      = "invalid"
+	ImportRefuted      = "refuted"
+	ImportInconclusive = "inconclusive"
+)
+
+// ImportResult is the result of importing a fraud report.
+type ImportResult struct {
+	Name     string                    `json:"name"`
+	Height   uint                      `json:"height,omitempty"`
+	Reporter string                    `json:"reporter"`
+	Result   string                    `json:"result"`
+	Message  string                    `json:"message"`
+	Report   *checkpoints.DenialReport `json:"report,omitempty"`
+}
+
+// Importer imports the fraud reports of the committee indexers in use into the deny list, after verifying the previous
+// commitment and the fraud commitment against the providers, and the fraud proof against Bitcoin. The providers must
+// include the accused committee indexer.
+type Importer struct {
+	BTC          *btcutl.Client
+	DenyListPath string
+	Indexers     *configs.CommitteeIndexers
+	Providers    []checkpoints.CheckpointProvider
+}
+
+// Import imports the fraud report, the fetches are retried until the context is done, so it should have a deadline.
+func (im *Importer) Import(ctx context.Context, r *FraudReport) *ImportResult {
+	ret := im.importReport(ctx, r)
+	metrics.FraudImports.WithLabelValues(ret.Result).Inc()
+	return ret
+}
+
+func (im *Importer) importReport(ctx context.Context, r *FraudReport) *ImportResult {
+	ret := &ImportResult{Reporter: r.Reporter}
+	result := func(result, format string, a ...any) *ImportResult {
+		ret.Result = result
+		ret.Message = fmt.Sprintf(format, a...)
+		return ret
+	}
+
+	d := r.Entry
+	if d == nil || d.Evidence == nil || d.Evidence.Proof == nil {
+		return result(ImportInvalid, "no fraud proof")
+	}
+	e, p := d.Evidence, d.Evidence.Proof
+	ret.Name, ret.Height = d.Name(), e.Height
+	if p.Height != e.Height || p.Hash != e.Hash || p.Commitment != e.FraudCommitment {
+		return result(ImportInvalid, "fraud proof doesn't match the evidence")
+	}
+	if e.Height == 0 {
+		return result(ImportInvalid, "invalid height")
+	}
+
+	items, err := configs.ReadDenyList(im.DenyListPath)
+	if err != nil {
+		return result(ImportInconclusive, "read deny list error: %v", err)
+	}
+	for _, item := range items {
+		if item.Name() == d.Name() && item.Evidence != nil &&
+			item.Evidence.Height == e.Height && item.Evidence.FraudCommitment == e.FraudCommitment {
+			return result(ImportDuplicate, "already in the deny list")
+		}
+	}
+
+	var source *configs.DenyList
+	for i := range im.Indexers.S3 {
+		if s := &im.Indexers.S3[i]; d.SourceS3 != nil && s.Equal(d.SourceS3) {
+			source = &configs.DenyList{SourceS3: s}
+		}
+	}
+	for i := range im.Indexers.DA {
+		if s := &im.Indexers.DA[i]; d.SourceDA != nil && s.Equal(d.SourceDA) {
+			source = &configs.DenyList{SourceDA: s}
+		}
+	}
+	if source == nil {
+		return result(ImportUnknown, "not a committee indexer in use")
+	}
+
+	// The fraud proof is only as good as its previous commitment, which must be the verified one.
+	prevHash, err := im.BTC.GetBlockHash(ctx, e.Height-1)
+	if err != nil {
+		return result(ImportInconclusive, "get block hash error: %v", err)
+	}
+	cks, _ := checkpoints.GetCheckpoints(ctx, im.Providers, e.Height-1, prevHash)
+	agree := 0
+	for _, ck := range cks {
+		if ck.Checkpoint.Commitment == p.PrevCommitment {
+			agree++
+		}
+	}
+	switch {
+	case agree == 0 && len(cks) > 0:
+		return result(ImportRefuted, "previous commitment differs from all providers")
+	case agree == 0:
+		return result(ImportInconclusive, "no providers to check the previous commitment")
+	case agree*2 <= len(cks):
+		return result(ImportInconclusive, "previous commitment agreed by %d of %d providers", agree, len(cks))
+	}
+
+	// The fraud proof doesn't prove the accused committee indexer served it, so it must still serve the fraud commitment,
+	// and the others must agree on a different one.
+	cks, _ = checkpoints.GetCheckpoints(ctx, im.Providers, e.Height, e.Hash)
+	var accused *configs.CheckpointExport
+	others, votes := 0, make(map[string]int)
+	for _, ck := range cks {
+		if ck.SourceS3 != nil && source.SourceS3 != nil && ck.SourceS3.Equal(source.SourceS3) ||
+			ck.SourceDA != nil && source.SourceDA != nil && ck.SourceDA.Equal(source.SourceDA) {
+			accused = ck
+			continue
+		}
+		others++
+		votes[ck.Checkpoint.Commitment]++
+	}
+	correct := 0
+	for c, n := range votes {
+		if c != e.FraudCommitment && n > correct {
+			correct = n
+		}
+	}
+	switch {
+	case accused == nil:
+		return result(ImportInconclusive, "no checkpoint from the accused committee indexer")
+	case accused.Checkpoint.Commitment != e.FraudCommitment:
+		return result(ImportRefuted, "fraud commitment not served by the accused committee indexer: commitment=%s",
+			accused.Checkpoint.Commitment)
+	case others == 0:
+		return result(ImportInconclusive, "no other providers to check the fraud commitment")
+	case votes[e.FraudCommitment] > correct:
+		return result(ImportRefuted, "fraud commitment agreed by %d of %d other providers", votes[e.FraudCommitment], others)
+	case correct*2 <= others:
+		return result(ImportInconclusive, "other providers disagree: agreed=%d, answered=%d", correct, others)
+	}
+
+	ret.Report = checkpoints.VerifyFraudProof(ctx, im.BTC, p)
+	switch ret.Report.Verdict {
+	case checkpoints.VerdictConfirmed:
+	case checkpoints.VerdictRefuted:
+		return result(ImportRefuted, "fraud proof refuted")
+	default:
+		return result(ImportInconclusive, "fraud proof %s", ret.Report.Verdict)
+	}
+
+	reason := fmt.Sprintf("imported from %s", r.Reporter)
+	if d.Reason != "" {
+		reason += ": " + d.Reason
+	}
+	entry := &configs.DenyList{
+		Evidence:  e,
+		SourceS3:  source.SourceS3,
+		SourceDA:  source.SourceDA,
+		CreatedAt: time.Now().UTC(),
+		Reason:    reason,
+	}
+	if err := configs.AppendDenyList(im.DenyListPath, entry); err != nil {
+		return result(ImportInconclusive, "append to deny list error: %v", err)
+	}
+	metrics.DenyListAdditions.Inc()
+	logs.Info.With("name", ret.Name, "height", e.Height, "reporter", r.Reporter).Print("Fraud report imported")
+	return result(ImportImported, "fraud proof confirmed")
+}
@@ -0,0 +1,250 @@
+package reports
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/apis"
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/ethereum/go-verkle"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+)
+
+func testEntry(source *configs.SourceS3) *configs.DenyList {
+	return &configs.DenyList{
+		SourceS3: source,
+		Evidence: &configs.Evidence{
+			Height:          100,
+			Hash:            "hash",
+			FraudCommitment: "fraud",
+			Proof:           &configs.FraudProof{Height: 100, Hash: "hash", Commitment: "fraud"},
+		},
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func TestParseFraudReports(t *testing.T) {
+	r := NewFraudReport("peer", "brc-20", "v0", testEntry(&configs.SourceS3{Name: "s3"}))
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ParseFraudReports(strings.NewReader(`{"name": "checkpoint"}` + "\n" + string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Reporter != "peer" || items[0].Entry.Evidence.Proof.Commitment != "fraud" {
+		t.Fatal("unexpected fraud reports", items)
+	}
+	if _, err := ParseFraudReports(strings.NewReader(`{"type": `)); err == nil {
+		t.Fatal("expected invalid JSON error")
+	}
+}
+
+func TestFraudPublisher_Webhook(t *testing.T) {
+	received := make(chan *FraudReport, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report FraudReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- &report
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	p := &FraudPublisher{Config: &configs.Fraud{NamespaceID: "0x01", Webhook: srv.URL}}
+	if !p.Enabled() {
+		t.Fatal("expected enabled by the webhook")
+	}
+	r := NewFraudReport("me", "brc-20", "v0", testEntry(&configs.SourceS3{Name: "s3"}))
+	if err := p.Publish(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; got.Type != FraudReportType || got.Entry.Name() != "s3" {
+		t.Fatal("unexpected fraud report", got)
+	}
+}
+
+func TestFraudPublisher_CheckPublishable(t *testing.T) {
+	var point verkle.Point
+	if err := point.SetBytes(make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	b := point.Bytes()
+	prev := base64.StdEncoding.EncodeToString(b[:])
+	empty := &apis.Brc20VerifiableLatestStateProofResponse{Result: &apis.Brc20VerifiableLatestStateProofResult{}}
+	entry := func(commitment string, failure configs.Failure) *configs.DenyList {
+		d := testEntry(&configs.SourceS3{Name: "s3"})
+		d.Evidence.Proof.PrevCommitment = prev
+		d.Evidence.Proof.Commitment = commitment
+		d.Evidence.Proof.Failure = failure
+		d.Evidence.Proof.StateProof = empty
+		return d
+	}
+	noProof := testEntry(&configs.SourceS3{Name: "s3"})
+	noProof.Evidence.Proof = nil
+
+	p := &FraudPublisher{Config: &configs.Fraud{}, BTC: btcutl.NewWithRPC(testRPC{100: "hash"})}
+	for _, c := range []struct {
+		name        string
+		entry       *configs.DenyList
+		publishable bool
+	}{
+		{"no evidence", &configs.DenyList{SourceS3: &configs.SourceS3{Name: "s3"}}, false},
+		{"no fraud proof", noProof, false},
+		{"unverified transfers", entry(prev, configs.FailureInvalidTransfers), false},
+		{"refuted", entry(prev, configs.FailureCommitmentMismatch), false},
+		{"confirmed", entry("fraud", configs.FailureCommitmentMismatch), true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if err := p.checkPublishable(context.Background(), c.entry); (err == nil) != c.publishable {
+				t.Fatalf("unexpected publishable: expected=%t, err=%v", c.publishable, err)
+			}
+		})
+	}
+}
+
+func TestImporter(t *testing.T) {
+	source := configs.SourceS3{Region: "us-west-2", Bucket: "b", Name: "s3"}
+	im := &Importer{
+		DenyListPath: filepath.Join(t.TempDir(), "deny.jsonlines"),
+		Indexers:     &configs.CommitteeIndexers{S3: []configs.SourceS3{source}},
+	}
+	ctx := context.Background()
+
+	if r := im.Import(ctx, NewFraudReport("peer", "brc-20", "v0", &configs.DenyList{SourceS3: &source})); r.Result != ImportInvalid {
+		t.Fatal("expected invalid without fraud proof", r)
+	}
+	mismatch := testEntry(&source)
+	mismatch.Evidence.Proof.Commitment = "other"
+	if r := im.Import(ctx, NewFraudReport("peer", "brc-20", "v0", mismatch)); r.Result != ImportInvalid {
+		t.Fatal("expected invalid with a mismatched fraud proof", r)
+	}
+	unknown := testEntry(&configs.SourceS3{Region: "us-west-2", Bucket: "b", Name: "other"})
+	if r := im.Import(ctx, NewFraudReport("peer", "brc-20", "v0", unknown)); r.Result != ImportUnknown {
+		t.Fatal("expected unknown committee indexer", r)
+	}
+	if err := configs.AppendDenyList(im.DenyListPath, testEntry(&source)); err != nil {
+		t.Fatal(err)
+	}
+	if r := im.Import(ctx, NewFraudReport("peer", "brc-20", "v0", testEntry(&source))); r.Result != ImportDuplicate {
+		t.Fatal("expected duplicate", r)
+	}
+}
+
+// testRPC serves the block hashes by height, and fails the other calls.
+type testRPC map[uint]string
+
+func (r testRPC) Call(_ context.Context, method string, params, out any) error {
+	if method != "getblockhash" {
+		return fmt.Errorf("connection refused: method=%s", method)
+	}
+	data, err := json.Marshal(map[string]any{"result": r[params.([]uint)[0]]})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// testProvider serves the commitments by height.
+type testProvider struct {
+	source      *configs.SourceS3
+	commitments map[uint]string
+}
+
+func (p *testProvider) Get(_ context.Context, height uint, hash string) (*configs.CheckpointExport, error) {
+	return &configs.CheckpointExport{
+		Checkpoint: &checkpoint.Checkpoint{Height: fmt.Sprint(height), Hash: hash, Commitment: p.commitments[height]},
+		SourceS3:   p.source,
+	}, nil
+}
+
+func TestImporter_Verify(t *testing.T) {
+	var point verkle.Point
+	if err := point.SetBytes(make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	b := point.Bytes()
+	prev := base64.StdEncoding.EncodeToString(b[:])
+	// Applying a state proof without transfers keeps the previous commitment, which isn't the fraud one.
+	empty := &apis.Brc20VerifiableLatestStateProofResponse{Result: &apis.Brc20VerifiableLatestStateProofResult{}}
+	errMsg := "forged"
+
+	accused := configs.SourceS3{Region: "us-west-2", Bucket: "b", Name: "accused"}
+	providers := func(accusedCommitment, otherCommitment string) []checkpoints.CheckpointProvider {
+		return []checkpoints.CheckpointProvider{
+			&testProvider{source: &accused, commitments: map[uint]string{99: prev, 100: accusedCommitment}},
+			&testProvider{source: &configs.SourceS3{Name: "s3-1"}, commitments: map[uint]string{99: prev, 100: otherCommitment}},
+			&testProvider{source: &configs.SourceS3{Name: "s3-2"}, commitments: map[uint]string{99: prev, 100: otherCommitment}},
+		}
+	}
+	entry := func(stateProof *apis.Brc20VerifiableLatestStateProofResponse) *configs.DenyList {
+		d := testEntry(&accused)
+		d.Evidence.Proof.PrevCommitment = prev
+		d.Evidence.Proof.StateProof = stateProof
+		return d
+	}
+	// The transfers of the state proof keeping the previous commitment can't be checked, Bitcoin RPC fails the blocks.
+	transfers := entry(&apis.Brc20VerifiableLatestStateProofResponse{Result: &apis.Brc20VerifiableLatestStateProofResult{
+		OrdTransfers: []apis.OrdTransferJSON{{NewSatpoint: "txid:0:0"}},
+	}})
+	transfers.Evidence.FraudCommitment = prev
+	transfers.Evidence.Proof.Commitment = prev
+	transfers.Evidence.Proof.Failure = configs.FailureInvalidTransfers
+
+	for _, c := range []struct {
+		name      string
+		providers []checkpoints.CheckpointProvider
+		entry     *configs.DenyList
+		result    string
+	}{
+		{"not served by the accused", providers("correct", "correct"), entry(empty), ImportRefuted},
+		{"agreed by the others", providers("fraud", "fraud"), entry(empty), ImportRefuted},
+		{"forged state proof", providers("fraud", "correct"), entry(&apis.Brc20VerifiableLatestStateProofResponse{Error: &errMsg}), ImportInconclusive},
+		{"unverified transfers", providers(prev, "correct"), transfers, ImportInconclusive},
+		{"imported", providers("fraud", "correct"), entry(empty), ImportImported},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			im := &Importer{
+				BTC:          btcutl.NewWithRPC(testRPC{99: "prev", 100: "hash"}),
+				DenyListPath: filepath.Join(t.TempDir(), "deny.jsonlines"),
+				Indexers:     &configs.CommitteeIndexers{S3: []configs.SourceS3{accused}},
+				Providers:    c.providers,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			r := im.Import(ctx, NewFraudReport("peer", "brc-20", "v0", c.entry))
+			if r.Result != c.result {
+				t.Fatalf("unexpected result: expected=%s, actual=%s, message=%s", c.result, r.Result, r.Message)
+			}
+
+			items, err := configs.ReadDenyList(im.DenyListPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.result != ImportImported {
+				if len(items) != 0 {
+					t.Fatal("unexpected deny list entries", items)
+				}
+				return
+			}
+			if len(items) != 1 || items[0].Name() != "accused" || items[0].Evidence.FraudCommitment != "fraud" ||
+				!strings.HasPrefix(items[0].Reason, "imported from peer") {
+				t.Fatal("unexpected deny list entries", items)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
+)
+
+const (
+	// DefaultMinBackoff is the interval of the first retry of a failed report, doubled on every failure.
+	DefaultMinBackoff = 5 * time.Second
+
+	// DefaultDoneSize is the number of reported checkpoints remembered to drop the duplicates.
+	DefaultDoneSize = 128
+)
+
+// Task is a checkpoint waiting to be reported to a sink.
+type Task struct {
+	Reporter    string                        `json:"reporter"`
+	Checkpoint  *checkpoints.SignedCheckpoint `json:"checkpoint"`
+	CreatedAt   time.Time                     `json:"createdAt"`
+	NextAttempt time.Time                     `json:"nextAttempt"`
+	Attempts    int                           `json:"attempts"`
+	LastError   string                        `json:"lastError,omitempty"`
+}
+
+func (t *Task) key() string {
+	return t.Reporter + "/" + t.Checkpoint.Height + "/" + t.Checkpoint.Hash
+}
+
+// QueueStatus is the status of the report queue.
+type QueueStatus struct {
+	Pending     int        `json:"pending"`
+	Reported    int        `json:"reported"`
+	Failures    int        `json:"failures"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	Tasks       []*Task    `json:"tasks"`
+}
+
+// queueFile is the persisted form of the queue.
+type queueFile struct {
+	Tasks []*Task  `json:"tasks"`
+	Done  []string `json:"done"`
+}
+
+// Queue is a persistent queue of the checkpoints to report, deduplicated by the sink, the height and the hash. Every
+// change is saved to the file, so the pending checkpoints survive restarts.
+type Queue struct {
+	path string
+
+	mu          sync.Mutex
+	tasks       []*Task
+	done        []string
+	reported    int
+	failures    int
+	lastSuccess time.Time
+	lastError   string
+	notify      chan struct{}
+}
+
+// OpenQueue reads the queue from the file, a missing file is an empty queue. An empty path keeps the queue in memory.
+func OpenQueue(path string) (*Queue, error) {
+	q := &Queue{path: path, notify: make(chan struct{}, 1)}
+	if path == "" {
+		return q, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f queueFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid report queue: path=%s, err=%v", path, err)
+	}
+	q.tasks = slices.DeleteFunc(f.Tasks, func(t *Task) bool {
+		return t.Checkpoint == nil || t.Checkpoint.Checkpoint == nil
+	})
+	for _, t := range q.tasks {
+		// Tasks of the previous versions are all uploaded to DA.
+		if t.Reporter == "" {
+			t.Reporter = "da"
+		}
+	}
+	q.done = f.Done
+	metrics.ReportQueueSize.Set(float64(len(q.tasks)))
+	return q, nil
+}
+
+// Push enqueues the checkpoint to report to every sink after a random delay up to the jitter. It returns the number of
+// the tasks enqueued, the duplicates of the pending or reported ones are dropped.
+func (q *Queue) Push(ck *checkpoints.SignedCheckpoint, reporters []string, jitter time.Duration) (int, error) {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, r := range reporters {
+		t := &Task{Reporter: r, Checkpoint: ck, CreatedAt: now, NextAttempt: now.Add(randDuration(jitter))}
+		key := t.key()
+		if slices.Contains(q.done, key) || slices.ContainsFunc(q.tasks, func(p *Task) bool { return p.key() == key }) {
+			continue
+		}
+		q.tasks = append(q.tasks, t)
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	q.signal()
+	return n, q.save()
+}
+
+// Status returns a snapshot of the queue.
+func (q *Queue) Status() *QueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := &QueueStatus{
+		Pending:   len(q.tasks),
+		Reported:  q.reported,
+		Failures:  q.failures,
+		LastError: q.lastError,
+		Tasks:     make([]*Task, 0, len(q.tasks)),
+	}
+	if !q.lastSuccess.IsZero() {
+		last := q.lastSuccess
+		s.LastSuccess = &last
+	}
+	for _, t := range q.tasks {
+		c := *t
+		s.Tasks = append(s.Tasks, &c)
+	}
+	return s
+}
+
+// next returns the task due first, and when it's due.
+func (q *Queue) next() (*Task, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var ret *Task
+	for _, t := range q.tasks {
+		if ret == nil || t.NextAttempt.Before(ret.NextAttempt) {
+			ret = t
+		}
+	}
+	if ret == nil {
+		return nil, time.Time{}
+	}
+	return ret, ret.NextAttempt
+}
+
+// finish removes the reported task, or schedules the retry of the failed one.
+func (q *Queue) finish(t *Task, err error, backoff time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	if err != nil {
+		t.Attempts++
+		t.LastError = err.Error()
+		t.NextAttempt = now.Add(backoff)
+		q.failures++
+		q.lastError = t.LastError
+		return q.save()
+	}
+	q.tasks = slices.DeleteFunc(q.tasks, func(p *Task) bool { return p == t })
+	q.done = append(q.done, t.key())
+	if l := len(q.done); l > DefaultDoneSize {
+		q.done = slices.Clone(q.done[l-DefaultDoneSize:])
+	}
+	q.reported++
+	q.lastSuccess = now
+	return q.save()
+}
+
+// drop removes the task without reporting it.
+func (q *Queue) drop(t *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tasks = slices.DeleteFunc(q.tasks, func(p *Task) bool { return p == t })
+	return q.save()
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// save writes the queue to a temporary file then renames it, so a crash never leaves a partial file.
+func (q *Queue) save() error {
+	metrics.ReportQueueSize.Set(float64(len(q.tasks)))
+	if q.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(&queueFile{Tasks: q.tasks, Done: q.done})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), q.path)
+}
+
+// Worker reports the checkpoints of the queue in the background, retrying the failed ones with exponential backoff.
+type Worker struct {
+	Queue     *Queue
+	Reporters []Reporter
+
+	// Jitter is the maximal random delay added to every retry.
+	Jitter     time.Duration
+	MaxBackoff time.Duration
+}
+
+// Names returns the names of the reporters to push the checkpoints for.
+func (w *Worker) Names() []string {
+	ret := make([]string, 0, len(w.Reporters))
+	for _, r := range w.Reporters {
+		ret = append(ret, r.Name())
+	}
+	return ret
+}
+
+// Run reports the due checkpoints until the context is done. The pending ones are kept in the queue for the next run,
+// and those of the sinks no longer configured are dropped.
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		t, due := w.Queue.next()
+		var wait <-chan time.Time
+		if t != nil {
+			wait = time.After(time.Until(due))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.Queue.notify:
+			continue
+		case <-wait:
+		}
+
+		fields := []any{"reporter", t.Reporter, "height", t.Checkpoint.Height, "hash", t.Checkpoint.Hash}
+		i := slices.IndexFunc(w.Reporters, func(r Reporter) bool { return r.Name() == t.Reporter })
+		if i < 0 {
+			logs.Warn.With(fields...).Print("Checkpoint dropped from the report queue, the sink is no longer configured")
+			if err := w.Queue.drop(t); err != nil {
+				logs.Error.Printf("Failed to save the report queue: %v", err)
+			}
+			continue
+		}
+
+		err := w.Reporters[i].Report(ctx, t.Checkpoint)
+		metrics.CheckpointReports.WithLabelValues(t.Reporter, metrics.Result(err)).Inc()
+		fields = append(fields, "attempts", t.Attempts+1)
+		if err != nil {
+			logs.Error.With(fields...).Printf("Unable to report the checkpoint: %v", err)
+		} else {
+			logs.Info.With(fields...).Print("Checkpoint successfully reported")
+		}
+		if err := w.Queue.finish(t, err, w.backoff(t.Attempts+1)); err != nil {
+			logs.Error.Printf("Failed to save the report queue: %v", err)
+		}
+	}
+}
+
+// backoff returns the interval before the next attempt after the failures.
+func (w *Worker) backoff(failures int) time.Duration {
+	d := DefaultMinBackoff
+	for i := 1; i < failures && d < w.MaxBackoff; i++ {
+		d *= 2
+	}
+	if w.MaxBackoff > 0 && d > w.MaxBackoff {
+		d = w.MaxBackoff
+	}
+	return d + randDuration(w.Jitter)
+}
+
+func randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
@@ -0,0 +1,110 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+)
+
+type fakeReporter struct {
+	name   string
+	report func(ck *checkpoint.Checkpoint) error
+}
+
+func (r *fakeReporter) Name() string {
+	return r.name
+}
+
+func (r *fakeReporter) Report(_ context.Context, ck *checkpoints.SignedCheckpoint) error {
+	return r.report(ck.Checkpoint)
+}
+
+func TestQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report-queue.json")
+	q, err := OpenQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ck := range []*checkpoint.Checkpoint{
+		{Height: "100", Hash: "a"},
+		{Height: "100", Hash: "a"},
+		{Height: "101", Hash: "b"},
+	} {
+		if _, err := q.Push(&checkpoints.SignedCheckpoint{Checkpoint: ck}, []string{"da"}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := q.Status(); s.Pending != 2 {
+		t.Fatal("expected duplicates dropped", s.Pending)
+	}
+
+	// Pending checkpoints survive restarts.
+	if q, err = OpenQueue(path); err != nil {
+		t.Fatal(err)
+	}
+	if s := q.Status(); s.Pending != 2 {
+		t.Fatal("expected pending checkpoints persisted", s.Pending)
+	}
+
+	fail := true
+	uploaded := make(chan string, 2)
+	w := &Worker{
+		Queue: q,
+		Reporters: []Reporter{&fakeReporter{name: "da", report: func(ck *checkpoint.Checkpoint) error {
+			if ck.Height == "100" && fail {
+				fail = false
+				return errors.New("unavailable")
+			}
+			uploaded <- ck.Height
+			return nil
+		}}},
+		MaxBackoff: time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	got := map[string]bool{<-uploaded: true, <-uploaded: true}
+	cancel()
+	<-done
+	if !got["100"] || !got["101"] {
+		t.Fatal("unexpected uploads", got)
+	}
+
+	s := q.Status()
+	if s.Pending != 0 || s.Reported != 2 || s.Failures != 1 || s.LastError != "unavailable" || s.LastSuccess == nil {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+	if n, _ := q.Push(
+		&checkpoints.SignedCheckpoint{Checkpoint: &checkpoint.Checkpoint{Height: "101", Hash: "b"}},
+		[]string{"da", "file:a"},
+		0,
+	); n != 1 {
+		t.Fatal("expected reported checkpoint dropped", n)
+	}
+}
+
+func TestWorker_Backoff(t *testing.T) {
+	w := &Worker{MaxBackoff: time.Minute}
+	for _, c := range []struct {
+		failures int
+		expected time.Duration
+	}{
+		{1, DefaultMinBackoff},
+		{2, 2 * DefaultMinBackoff},
+		{3, 4 * DefaultMinBackoff},
+		{10, time.Minute},
+	} {
+		if d := w.backoff(c.failures); d != c.expected {
+			t.Fatalf("unexpected backoff: failures=%d, expected=%s, actual=%s", c.failures, c.expected, d)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/httputl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
+)
+
+// Reporter reports the verified checkpoints to a sink.
+type Reporter interface {
+	// Name identifies the sink in the report queue, logs and metrics.
+	Name() string
+	Report(ctx context.Context, ck *checkpoints.SignedCheckpoint) error
+}
+
+// NewReporters creates the reporters of the sinks.
+func NewReporters(sinks *configs.Sinks) ([]Reporter, error) {
+	var ret []Reporter
+	for i := range sinks.S3 {
+		r, err := NewS3(&sinks.S3[i])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	for _, w := range sinks.Webhook {
+		r, err := NewWebhook(w)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	for _, f := range sinks.File {
+		ret = append(ret, NewFile(f))
+	}
+	return ret, nil
+}
+
+// DA uploads the checkpoints to the namespace of the report config.
+type DA struct {
+	Config *configs.Report
+}
+
+func NewDA(c *configs.Report) *DA {
+	return &DA{Config: c}
+}
+
+func (r *DA) Name() string {
+	return "da"
+}
+
+// Report uploads the checkpoint, bounded by the report timeout.
+func (r *DA) Report(ctx context.Context, ck *checkpoints.SignedCheckpoint) error {
+	data, err := json.Marshal(ck)
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.Config.Timeout.Duration)
+	defer cancel()
+	err = UploadDA(ctx, data, r.Config.PrivateKey, r.Config.GasCoupon, r.Config.NamespaceID, r.Config.Network)
+	metrics.DAUploads.WithLabelValues(metrics.Result(err)).Inc()
+	return err
+}
+
+// S3 uploads the checkpoints to an S3 compatible bucket, named the same as those read by checkpoints.S3.
+type S3 struct {
+	Config *configs.SinkS3
+	client *s3.Client
+}
+
+func NewS3(c *configs.SinkS3) (*S3, error) {
+	opts := []func(*config.LoadOptions) error{config.WithRegion(c.Region)}
+	if c.AccessKeyID != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, ""),
+		))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS config: bucket=%s, err=%v", c.Bucket, err)
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if c.Endpoint != "" {
+			o.BaseEndpoint = aws.String(c.Endpoint)
+		}
+		o.UsePathStyle = c.PathStyle
+	})
+	return &S3{Config: c, client: client}, nil
+}
+
+func (r *S3) Name() string {
+	return "s3:" + r.Config.Bucket
+}
+
+// ObjectKey returns the object name of the checkpoint.
+func ObjectKey(ck *checkpoints.SignedCheckpoint) string {
+	return fmt.Sprintf("checkpoint-%s-%s-%s-%s.json", ck.Name, ck.MetaProtocol, ck.Height, ck.Hash)
+}
+
+func (r *S3) Report(ctx context.Context, ck *checkpoints.SignedCheckpoint) error {
+	data, err := json.Marshal(ck)
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint error: %v", err)
+	}
+	key := ObjectKey(ck)
+	if _, err := r.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r.Config.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return fmt.Errorf("failed to upload to S3: bucket=%s, key=%s, err=%v", r.Config.Bucket, key, err)
+	}
+	return nil
+}
+
+// Webhook posts the checkpoints in JSON to the URL.
+type Webhook struct {
+	URL *url.URL
+}
+
+func NewWebhook(rawURL string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook: rawURL=%s, err=%v", rawURL, err)
+	}
+	return &Webhook{URL: u}, nil
+}
+
+func (r *Webhook) Name() string {
+	return "webhook:" + r.URL.Redacted()
+}
+
+func (r *Webhook) Report(ctx context.Context, ck *checkpoints.SignedCheckpoint) error {
+	return httputl.PostJSON(ctx, r.URL, ck, nil)
+}
+
+// File appends the checkpoints in JSON lines to the file.
+type File struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFile(path string) *File {
+	return &File{Path: path}
+}
+
+func (r *File) Name() string {
+	return "file:" + r.Path
+}
+
+func (r *File) Report(_ context.Context, ck *checkpoints.SignedCheckpoint) error {
+	data, err := json.Marshal(ck)
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint error: %v", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
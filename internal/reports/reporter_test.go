@@ -0,0 +1,40 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.jsonl")
+	r := NewFile(path)
+	for _, h := range []string{"100", "101"} {
+		if err := r.Report(context.Background(), &checkpoints.SignedCheckpoint{Checkpoint: &checkpoint.Checkpoint{Height: h, Hash: "a"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatal("expected a line per checkpoint", len(lines))
+	}
+	var ck checkpoint.Checkpoint
+	if err := json.Unmarshal([]byte(lines[1]), &ck); err != nil {
+		t.Fatal(err)
+	}
+	if ck.Height != "101" {
+		t.Fatal("unexpected checkpoint", ck.Height)
+	}
+}
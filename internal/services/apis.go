@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,29 +11,82 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
 	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
 	"github.com/RiemaLabs/modular-indexer-light/internal/states"
 )
 
-const DefaultAddr = ":8080"
+const (
+	DefaultAddr = ":8080"
 
-func StartService(enableDebug bool, addr string) {
+	// DefaultShutdownTimeout is the maximal time to drain the in-flight requests on shutdown.
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// StartService serves the public APIs on the address and the admin APIs on their own listener until the context is
+// done, then stops accepting connections and waits for the in-flight requests to finish.
+func StartService(ctx context.Context, enableDebug bool, addr string, admin *configs.Admin) error {
 	if !enableDebug {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	r := gin.Default()
-	r.Use(
-		gin.Recovery(),
-		gin.Logger(),
-		cors.New(cors.Config{
-			AllowOrigins:     []string{"*"},
-			AllowMethods:     []string{"POST", "GET"},
-			AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length"},
-			AllowCredentials: true,
-			MaxAge:           12 * time.Hour,
-		}),
-	)
+	gin.DefaultWriter = logs.Debug.Writer()
+	gin.DefaultErrorWriter = logs.Error.Writer()
+
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	servers := []*http.Server{
+		{Addr: addr, Handler: NewRouter()},
+		{Addr: admin.ListenAddr, Handler: NewAdminRouter(admin.Token)},
+	}
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() { errs <- srv.ListenAndServe() }()
+	}
+	logs.Info.With("addr", addr, "admin", admin.ListenAddr).Print("API service started")
+
+	var err error
+	running := len(servers)
+	select {
+	case err = <-errs:
+		err = fmt.Errorf("server exit with error: %v", err)
+		running--
+	case <-ctx.Done():
+	}
+
+	logs.Info.Print("Draining in-flight requests...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil && err == nil {
+			err = fmt.Errorf("server shutdown error: addr=%s, err=%v", srv.Addr, shutdownErr)
+		}
+	}
+	for ; running > 0; running-- {
+		if exitErr := <-errs; !errors.Is(exitErr, http.ErrServerClosed) && err == nil {
+			err = exitErr
+		}
+	}
+	return err
+}
+
+func newEngine() *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery(), RequestID, Trace, AccessLog)
+	return r
+}
+
+// NewRouter returns the handler of the public APIs, which never change the state of the light indexer.
+func NewRouter() http.Handler {
+	r := newEngine()
+	r.Use(cors.New(cors.Config{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{"POST", "GET"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Content-Length", "X-Request-Id"},
+		MaxAge:       12 * time.Hour,
+	}))
 	r.GET("/v1/brc20_verifiable/light/state", func(c *gin.Context) {
 		c.JSON(http.StatusOK, struct {
 			State fmt.Stringer `json:"state"`
@@ -40,20 +94,41 @@ func StartService(enableDebug bool, addr string) {
 			State: states.Status(states.S.Status.Load()),
 		})
 	})
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	r.GET("/healthz", HandleHealthz)
+	r.GET("/readyz", HandleReadyz)
+	r.GET("/v1/brc20_verifiable/light/events", HandleStreamEvents)
 	g := r.Group("v1")
 	{
 		g.Use(CheckState)
 		g.GET("/brc20_verifiable/light/block_height", func(c *gin.Context) { c.String(http.StatusOK, strconv.Itoa(int(states.S.CurrentHeight()))) })
 		g.GET("/brc20_verifiable/light/current_balance_of_wallet", HandleGetCurrentBalanceOfWallet)
 		g.GET("/brc20_verifiable/light/current_balance_of_pkscript", HandleGetCurrentBalanceOfPkscript)
+		g.POST("/brc20_verifiable/light/balances", HandleGetBalances)
 		g.GET("/brc20_verifiable/light/checkpoints", func(c *gin.Context) { c.JSON(http.StatusOK, states.S.CurrentCheckpoints()) })
 		g.GET("/brc20_verifiable/light/last_checkpoint", func(c *gin.Context) { c.JSON(http.StatusOK, states.S.LastCheckpoint()) })
 	}
+	return r
+}
 
-	if addr == "" {
-		addr = DefaultAddr
-	}
-	if err := r.Run(addr); !errors.Is(err, http.ErrServerClosed) {
-		logs.Error.Fatalln("Server exit with error:", err)
+// NewAdminRouter returns the handler of the admin APIs requiring the bearer token, empty means no authentication for
+// a listener on the loopback interface. They're not for browsers, so there is no CORS.
+func NewAdminRouter(token string) http.Handler {
+	r := newEngine()
+	admin := r.Group("admin")
+	{
+		admin.Use(AdminAuth(token))
+		admin.GET("/log_level", HandleGetLogLevel)
+		admin.PUT("/log_level", HandleSetLogLevel)
+		admin.GET("/deny_list", HandleListDenyList)
+		admin.POST("/deny_list", HandleAddDenyList)
+		admin.DELETE("/deny_list/:name", HandleRemoveDenyList)
+		admin.POST("/deny_list/verify", HandleVerifyDenyList)
+		admin.POST("/fraud_reports", HandleImportFraudReport)
+		admin.GET("/report", HandleReportStatus)
+		admin.GET("/watches", HandleListWatches)
+		admin.POST("/watches", HandleAddWatch)
+		admin.DELETE("/watches/:id", HandleRemoveWatch)
 	}
+	return r
 }
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminRouter(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		handler http.Handler
+		auth    string
+		status  int
+	}{
+		{"public", NewRouter(), "Bearer secret", http.StatusNotFound},
+		{"no token", NewAdminRouter("secret"), "", http.StatusUnauthorized},
+		{"wrong token", NewAdminRouter("secret"), "Bearer wrong", http.StatusUnauthorized},
+		{"token", NewAdminRouter("secret"), "Bearer secret", http.StatusOK},
+		{"loopback", NewAdminRouter(""), "", http.StatusOK},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/log_level", nil)
+			if c.auth != "" {
+				req.Header.Set("Authorization", c.auth)
+			}
+			rec := httptest.NewRecorder()
+			c.handler.ServeHTTP(rec, req)
+			if rec.Code != c.status {
+				t.Fatalf("unexpected status: expected=%d, actual=%d", c.status, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// MaxBatchBalances is the maximum number of queries accepted in one batch request.
+	MaxBatchBalances = 1000
+
+	// DefaultBatchConcurrency is the number of in-flight committee indexer requests of one batch.
+	DefaultBatchConcurrency = 16
+)
+
+type (
+	BalancesRequest struct {
+		Queries []*BalanceQuery `json:"queries"`
+	}
+
+	// BalanceQuery is a balance query of a tick, either by wallet or by PkScript.
+	BalanceQuery struct {
+		Tick     string `json:"tick"`
+		Wallet   string `json:"wallet,omitempty"`
+		Pkscript string `json:"pkscript,omitempty"`
+	}
+
+	BalancesResponse struct {
+		Height     string           `json:"height"`
+		Hash       string           `json:"hash"`
+		Commitment string           `json:"commitment"`
+		Results    []*BalanceResult `json:"results"`
+	}
+
+	// BalanceResult is the verified result of a BalanceQuery, Result is the committee indexer response and holds
+	// either a wallet or a PkScript balance.
+	BalanceResult struct {
+		*BalanceQuery
+		Result any     `json:"result,omitempty"`
+		Error  *string `json:"error,omitempty"`
+	}
+)
+
+func (q *BalanceQuery) Validate() error {
+	if q.Tick == "" {
+		return errors.New("empty tick")
+	}
+	if (q.Wallet == "") == (q.Pkscript == "") {
+		return errors.New("exactly one of wallet and pkscript is required")
+	}
+	return nil
+}
+
+func HandleGetBalances(c *gin.Context) {
+	var req BalancesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if l := len(req.Queries); l == 0 || l > MaxBatchBalances {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("invalid number of queries: expected=1..%d, actual=%d", MaxBatchBalances, l),
+		})
+		return
+	}
+	for i, q := range req.Queries {
+		if q == nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid query: index=%d, err=null query", i)})
+			return
+		}
+		if err := q.Validate(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid query: index=%d, err=%v", i, err)})
+			return
+		}
+	}
+
+	cks, current, err := QueryCheckpoints(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var results []*BalanceResult
+	if current {
+		if results, err = GetBalances(c.Request.Context(), cks, req.Queries); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		results = GetRetainedBalances(cks, req.Queries)
+	}
+	ck := cks[0]
+	c.JSON(http.StatusOK, &BalancesResponse{
+		Height:     ck.Height,
+		Hash:       ck.Hash,
+		Commitment: ck.Commitment,
+		Results:    results,
+	})
+}
+
+// GetBalances fetches the balances of all queries concurrently, every proof is verified against the same commitment of
+// the checkpoints and each query falls back among their committee indexers. The returned error is non-nil only when
+// the checkpoints are unusable, per-query errors are reported in results.
+func GetBalances(ctx context.Context, cks []*checkpoint.Checkpoint, queries []*BalanceQuery) ([]*BalanceResult, error) {
+	sources, point, err := newSources(cks)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*BalanceResult, len(queries))
+	sem := make(chan struct{}, DefaultBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ret := &BalanceResult{BalanceQuery: q}
+			results[i] = ret
+
+			var (
+				result any
+				err    error
+			)
+			if q.Wallet != "" {
+				result, err = hedge(ctx, sources, func(ctx context.Context, src *source) (any, error) {
+					return getBalanceOfWallet(ctx, src.cl, src.ck, point, q.Tick, q.Wallet)
+				})
+			} else {
+				result, err = hedge(ctx, sources, func(ctx context.Context, src *source) (any, error) {
+					return getBalanceOfPkscript(ctx, src.cl, src.ck, point, q.Tick, q.Pkscript)
+				})
+			}
+			if err != nil {
+				msg := err.Error()
+				ret.Error = &msg
+				return
+			}
+			ret.Result = result
+		}()
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// GetRetainedBalances is like GetBalances but returns the retained balances at the historical height of the
+// checkpoints, see GetRetainedBalanceOfWallet.
+func GetRetainedBalances(cks []*checkpoint.Checkpoint, queries []*BalanceQuery) []*BalanceResult {
+	results := make([]*BalanceResult, len(queries))
+	for i, q := range queries {
+		ret := &BalanceResult{BalanceQuery: q}
+		results[i] = ret
+
+		var (
+			result any
+			err    error
+		)
+		if q.Wallet != "" {
+			result, err = GetRetainedBalanceOfWallet(cks, q.Tick, q.Wallet)
+		} else {
+			result, err = GetRetainedBalanceOfPkscript(cks, q.Tick, q.Pkscript)
+		}
+		if err != nil {
+			msg := err.Error()
+			ret.Error = &msg
+			continue
+		}
+		ret.Result = result
+	}
+	return results
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/apis"
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/RiemaLabs/modular-indexer-committee/ord"
+	"github.com/RiemaLabs/modular-indexer-committee/ord/stateless"
+	"github.com/ethereum/go-verkle"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+const testTick = "ordi"
+
+// testCommittee is a committee indexer serving the verifiable balances of its pkscripts: the balance of pkscript-i is
+// i and the wallet of it is wallet-i. The other pkscripts and wallets are not found.
+type testCommittee struct {
+	commitment string
+	proofs     map[string]string
+
+	inFlight, maxInFlight atomic.Int32
+}
+
+func newTestCommittee(t *testing.T, n int) *testCommittee {
+	root := verkle.New()
+	for i := range n {
+		balance, err := apis.ParseBalance(strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range balanceKeys(testPkscript(i)) {
+			if err := root.Insert(key, balance, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	b := root.Commit().Bytes()
+	c := &testCommittee{commitment: base64.StdEncoding.EncodeToString(b[:]), proofs: make(map[string]string)}
+	for i := range n {
+		proof, _, _, _, err := verkle.MakeVerkleMultiProof(root, nil, balanceKeys(testPkscript(i)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vProof, _, err := verkle.SerializeProof(proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := vProof.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.proofs[testPkscript(i)] = base64.StdEncoding.EncodeToString(data)
+	}
+	return c
+}
+
+func testPkscript(i int) string { return fmt.Sprintf("pkscript-%d", i) }
+
+func balanceKeys(pkscript string) [][]byte {
+	return [][]byte{
+		stateless.GetTickPkscriptHash(testTick, ord.Pkscript(pkscript), stateless.AvailableBalancePkscript),
+		stateless.GetTickPkscriptHash(testTick, ord.Pkscript(pkscript), stateless.OverallBalancePkscript),
+	}
+}
+
+func (c *testCommittee) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for n, m := c.inFlight.Add(1), c.maxInFlight.Load(); n > m && !c.maxInFlight.CompareAndSwap(m, n); {
+		m = c.maxInFlight.Load()
+	}
+	defer c.inFlight.Add(-1)
+	// Responses complete out of order.
+	time.Sleep(time.Duration(rand.IntN(3)) * time.Millisecond)
+
+	q := r.URL.Query()
+	pkscript := q.Get("pkscript")
+	if wallet := q.Get("wallet"); wallet != "" {
+		pkscript = "pkscript-" + wallet[len("wallet-"):]
+	}
+	proof, ok := c.proofs[pkscript]
+	if !ok || q.Get("tick") != testTick {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	balance := pkscript[len("pkscript-"):]
+	var rsp any = &apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse{
+		Result: &apis.Brc20VerifiableCurrentBalanceOfPkscriptResult{AvailableBalance: balance, OverallBalance: balance},
+		Proof:  &proof,
+	}
+	if r.URL.Path == "/v1/brc20_verifiable/current_balance_of_wallet" {
+		rsp = &apis.Brc20VerifiableCurrentBalanceOfWalletResponse{
+			Result: &apis.Brc20VerifiableCurrentBalanceOfWalletResult{
+				AvailableBalance: balance,
+				OverallBalance:   balance,
+				Pkscript:         pkscript,
+			},
+			Proof: &proof,
+		}
+	}
+	_ = json.NewEncoder(w).Encode(rsp)
+}
+
+// initTestState verifies the checkpoint of the committee indexer at the height as the current one.
+func initTestState(t *testing.T, url, commitment string, height uint) {
+	ck := &checkpoint.Checkpoint{Height: strconv.Itoa(int(height)), Hash: "hash", Commitment: commitment, URL: url}
+	last := &configs.CheckpointExport{Checkpoint: &checkpoint.Checkpoint{Height: strconv.Itoa(int(height - 1))}}
+	states.S = states.New(nil, "", []checkpoints.CheckpointProvider{(*configs.SourceRaw)(ck)}, last, 1, 0, 0)
+	if err := states.S.UpdateCheckpoints(context.Background(), height, ck.Hash); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleGetBalances(t *testing.T) {
+	committee := newTestCommittee(t, 4)
+	srv := httptest.NewServer(committee)
+	defer srv.Close()
+	initTestState(t, srv.URL, committee.commitment, 100)
+
+	// The queries of unknown pkscripts fail without verifying proofs, which is slow.
+	queries := func(n int) []*BalanceQuery {
+		ret := make([]*BalanceQuery, n)
+		for i := range ret {
+			ret[i] = &BalanceQuery{Tick: testTick, Pkscript: "pkscript-unknown"}
+		}
+		return ret
+	}
+	post := func(path string, body []byte) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		NewRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+		return rec
+	}
+	marshal := func(req *BalancesRequest) []byte {
+		data, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	const path = "/v1/brc20_verifiable/light/balances"
+	for _, c := range []struct {
+		name   string
+		path   string
+		body   []byte
+		status int
+	}{
+		{"empty body", path, nil, http.StatusBadRequest},
+		{"no queries", path, marshal(&BalancesRequest{}), http.StatusBadRequest},
+		{"null query", path, []byte(`{"queries":[null]}`), http.StatusBadRequest},
+		{"invalid query", path, marshal(&BalancesRequest{Queries: []*BalanceQuery{{Tick: testTick}}}), http.StatusBadRequest},
+		{"too many queries", path, marshal(&BalancesRequest{Queries: queries(MaxBatchBalances + 1)}), http.StatusBadRequest},
+		{"height not retained", path + "?height=98", marshal(&BalancesRequest{Queries: queries(1)}), http.StatusBadRequest},
+		{"current height", path + "?height=100", marshal(&BalancesRequest{Queries: queries(1)}), http.StatusOK},
+		{"max queries", path, marshal(&BalancesRequest{Queries: queries(MaxBatchBalances)}), http.StatusOK},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if rec := post(c.path, c.body); rec.Code != c.status {
+				t.Fatalf("unexpected status: expected=%d, actual=%d, body=%s", c.status, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	t.Run("mixed results", func(t *testing.T) {
+		req := &BalancesRequest{Queries: []*BalanceQuery{
+			{Tick: testTick, Pkscript: testPkscript(1)},
+			{Tick: testTick, Pkscript: "pkscript-unknown"},
+			{Tick: testTick, Wallet: "wallet-2"},
+			{Tick: "unknown", Pkscript: testPkscript(3)},
+		}}
+		rec := post(path, marshal(req))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status: expected=%d, actual=%d, body=%s", http.StatusOK, rec.Code, rec.Body)
+		}
+		var rsp struct {
+			Height     string `json:"height"`
+			Commitment string `json:"commitment"`
+			Results    []struct {
+				BalanceQuery
+				Result *apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse `json:"result"`
+				Error  *string                                               `json:"error"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &rsp); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Height != "100" || rsp.Commitment != committee.commitment || len(rsp.Results) != len(req.Queries) {
+			t.Fatal(rec.Body)
+		}
+		for i, expected := range []string{"1", "", "2", ""} {
+			r := rsp.Results[i]
+			if r.BalanceQuery != *req.Queries[i] {
+				t.Fatalf("unexpected query: index=%d, query=%+v", i, r.BalanceQuery)
+			}
+			if expected == "" {
+				if r.Error == nil || r.Result != nil {
+					t.Fatalf("expected error: index=%d, body=%s", i, rec.Body)
+				}
+				continue
+			}
+			if r.Error != nil || r.Result == nil || r.Result.Result.AvailableBalance != expected {
+				t.Fatalf("unexpected result: index=%d, body=%s", i, rec.Body)
+			}
+		}
+	})
+
+	t.Run("historical height", func(t *testing.T) {
+		next := &checkpoint.Checkpoint{Height: "101", Hash: "hash-101", Commitment: "commitment-101", URL: srv.URL}
+		if err := states.S.SetProviders([]checkpoints.CheckpointProvider{(*configs.SourceRaw)(next)}, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := states.S.UpdateCheckpoints(context.Background(), 101, next.Hash); err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the balances verified while 100 was the current height are retained.
+		req := &BalancesRequest{Queries: []*BalanceQuery{
+			{Tick: testTick, Pkscript: testPkscript(1)},
+			{Tick: testTick, Pkscript: testPkscript(2)},
+			{Tick: testTick, Wallet: "wallet-2"},
+		}}
+		rec := post(path+"?height=100", marshal(req))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status: expected=%d, actual=%d, body=%s", http.StatusOK, rec.Code, rec.Body)
+		}
+		var rsp BalancesResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &rsp); err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Height != "100" || rsp.Commitment != committee.commitment || len(rsp.Results) != len(req.Queries) {
+			t.Fatal(rec.Body)
+		}
+		if r := rsp.Results; r[0].Error != nil || r[1].Error == nil || r[2].Error != nil {
+			t.Fatal(rec.Body)
+		}
+	})
+}
+
+func TestGetBalances_Order(t *testing.T) {
+	const n = 2 * DefaultBatchConcurrency
+	committee := newTestCommittee(t, n)
+	srv := httptest.NewServer(committee)
+	defer srv.Close()
+
+	queries := make([]*BalanceQuery, n)
+	for i := range queries {
+		queries[i] = &BalanceQuery{Tick: testTick, Pkscript: testPkscript(i)}
+	}
+	ck := &checkpoint.Checkpoint{Height: "100", Commitment: committee.commitment, URL: srv.URL}
+	results, err := GetBalances(context.Background(), []*checkpoint.Checkpoint{ck}, queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if r.BalanceQuery != queries[i] || r.Error != nil {
+			t.Fatalf("unexpected result: index=%d, result=%+v", i, r)
+		}
+		balance := r.Result.(*apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse).Result.AvailableBalance
+		if balance != strconv.Itoa(i) {
+			t.Fatalf("unexpected balance: index=%d, balance=%s", i, balance)
+		}
+	}
+	if m := committee.maxInFlight.Load(); m > DefaultBatchConcurrency {
+		t.Fatalf("too many requests in flight: expected=%d, actual=%d", DefaultBatchConcurrency, m)
+	}
+}
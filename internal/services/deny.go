@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
+	"github.com/RiemaLabs/modular-indexer-light/internal/reports"
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+const (
+	// DefaultDenialVerifyTimeout is the timeout of re-verifying one deny list entry.
+	DefaultDenialVerifyTimeout = time.Minute
+
+	// DefaultDenyListVerifyTimeout is the timeout of re-verifying the entries in one request.
+	DefaultDenyListVerifyTimeout = 5 * time.Minute
+)
+
+// denyListVerifying is held while the entries are re-verified, so at most one request loads the Bitcoin RPC and the
+// committee indexers at a time.
+var denyListVerifying sync.Mutex
+
+var denyListPath string
+
+// InitDenyList sets the deny list managed by the admin APIs.
+func InitDenyList(path string) {
+	denyListPath = path
+}
+
+// DenyListEntry is a deny list entry with its expiration.
+type DenyListEntry struct {
+	*configs.DenyList
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Expired   bool       `json:"expired"`
+}
+
+// NewDenyListEntries returns the entries with their expiration according to the expiry.
+func NewDenyListEntries(items []*configs.DenyList, expiry time.Duration) []*DenyListEntry {
+	now := time.Now()
+	ret := make([]*DenyListEntry, 0, len(items))
+	for _, d := range items {
+		e := &DenyListEntry{DenyList: d, Name: d.Name(), Expired: d.Expired(expiry, now)}
+		if at := d.ExpiresAt(expiry); !at.IsZero() {
+			e.ExpiresAt = &at
+		}
+		ret = append(ret, e)
+	}
+	return ret
+}
+
+func HandleListDenyList(c *gin.Context) {
+	items, err := configs.ReadDenyList(denyListPath)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, NewDenyListEntries(items, configs.Applied().Verification.DenyExpiry.Duration))
+}
+
+func HandleAddDenyList(c *gin.Context) {
+	var req struct {
+		Name   string `json:"name"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d, err := configs.Applied().CommitteeIndexers.NewDenial(req.Name, req.Reason)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err := configs.AppendDenyList(denyListPath, d); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	metrics.DenyListAdditions.Inc()
+	logs.Info.With("name", req.Name, "reason", req.Reason).Print("Committee indexer added to the deny list")
+	c.JSON(http.StatusOK, d)
+}
+
+func HandleRemoveDenyList(c *gin.Context) {
+	name := c.Param("name")
+	removed, err := configs.RemoveDenyList(denyListPath, func(d *configs.DenyList) bool { return d.Name() == name })
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(removed) == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "committee indexer not in the deny list"})
+		return
+	}
+	logs.Info.With("name", name, "entries", len(removed)).Print("Committee indexer removed from the deny list")
+	c.JSON(http.StatusOK, removed)
+}
+
+// HandleVerifyDenyList re-verifies the evidence of the entries, or only those of the committee indexer in the `name`
+// query.
+//
+// Only one request re-verifies at a time, the others get 429, and the request is bounded by
+// DefaultDenyListVerifyTimeout in total.
+func HandleVerifyDenyList(c *gin.Context) {
+	if !denyListVerifying.TryLock() {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "deny list verification in progress"})
+		return
+	}
+	defer denyListVerifying.Unlock()
+
+	items, err := configs.ReadDenyList(denyListPath)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	name := c.Query("name")
+	reqCtx, reqCancel := context.WithTimeout(c.Request.Context(), DefaultDenyListVerifyTimeout)
+	defer reqCancel()
+	reports := make([]*checkpoints.DenialReport, 0, len(items))
+	for _, d := range items {
+		if name != "" && d.Name() != name {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(reqCtx, DefaultDenialVerifyTimeout)
+		reports = append(reports, checkpoints.VerifyDenial(
+			ctx,
+			btcutl.BTC,
+			d,
+			states.S.CheckpointProviders(),
+			configs.C.Verification.MetaProtocol,
+		))
+		cancel()
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// HandleImportFraudReport imports the fraud report in the body after verifying it, so it could be the webhook of the
+// fraud reports of other Light Indexers.
+func HandleImportFraudReport(c *gin.Context) {
+	var r reports.FraudReport
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	im := &reports.Importer{
+		BTC:          btcutl.BTC,
+		DenyListPath: denyListPath,
+		Indexers:     &configs.Applied().CommitteeIndexers,
+		Providers:    states.S.CheckpointProviders(),
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), DefaultDenialVerifyTimeout)
+	defer cancel()
+	c.JSON(http.StatusOK, im.Import(ctx, &r))
+}
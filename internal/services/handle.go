@@ -3,18 +3,23 @@ package services
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/RiemaLabs/modular-indexer-committee/apis"
 	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
 	"github.com/ethereum/go-verkle"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/RiemaLabs/modular-indexer-light/internal/clients/committee"
 	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
 	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
 )
 
 const errMsgBalanceNotFound = "proof of absence"
@@ -29,11 +34,21 @@ func CheckState(c *gin.Context) {
 }
 
 func HandleGetCurrentBalanceOfWallet(c *gin.Context) {
-	balance, err := GetCurrentBalanceOfWallet(
-		states.S.CurrentFirstCheckpoint().Checkpoint,
-		c.DefaultQuery("tick", ""),
-		c.DefaultQuery("wallet", ""),
-	)
+	cks, current, err := QueryCheckpoints(c)
+	if err != nil {
+		msg := err.Error()
+		c.AbortWithStatusJSON(http.StatusBadRequest, apis.Brc20VerifiableCurrentBalanceOfWalletResponse{
+			Error: &msg,
+		})
+		return
+	}
+	tick, wallet := c.DefaultQuery("tick", ""), c.DefaultQuery("wallet", "")
+	var balance *apis.Brc20VerifiableCurrentBalanceOfWalletResponse
+	if current {
+		balance, err = GetCurrentBalanceOfWallet(c.Request.Context(), cks, tick, wallet)
+	} else {
+		balance, err = GetRetainedBalanceOfWallet(cks, tick, wallet)
+	}
 	if err != nil {
 		msg := err.Error()
 		c.AbortWithStatusJSON(http.StatusBadRequest, apis.Brc20VerifiableCurrentBalanceOfWalletResponse{
@@ -45,11 +60,21 @@ func HandleGetCurrentBalanceOfWallet(c *gin.Context) {
 }
 
 func HandleGetCurrentBalanceOfPkscript(c *gin.Context) {
-	balance, err := GetCurrentBalanceOfPkscript(
-		states.S.CurrentFirstCheckpoint().Checkpoint,
-		c.DefaultQuery("tick", ""),
-		c.DefaultQuery("pkscript", ""),
-	)
+	cks, current, err := QueryCheckpoints(c)
+	if err != nil {
+		msg := err.Error()
+		c.AbortWithStatusJSON(http.StatusBadRequest, apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse{
+			Error: &msg,
+		})
+		return
+	}
+	tick, pkscript := c.DefaultQuery("tick", ""), c.DefaultQuery("pkscript", "")
+	var balance *apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse
+	if current {
+		balance, err = GetCurrentBalanceOfPkscript(c.Request.Context(), cks, tick, pkscript)
+	} else {
+		balance, err = GetRetainedBalanceOfPkscript(cks, tick, pkscript)
+	}
 	if err != nil {
 		msg := err.Error()
 		c.AbortWithStatusJSON(http.StatusBadRequest, apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse{
@@ -60,68 +85,158 @@ func HandleGetCurrentBalanceOfPkscript(c *gin.Context) {
 	c.JSON(http.StatusOK, balance)
 }
 
-func GetCurrentBalanceOfWallet(ck *checkpoint.Checkpoint, tick, wallet string) (*apis.Brc20VerifiableCurrentBalanceOfWalletResponse, error) {
-	cl, err := committee.New(ck.URL)
+// QueryCheckpoints returns the verified checkpoints at the optional `height` query, and whether they're the current
+// ones. The balances at a historical height are the retained ones, see GetRetainedBalanceOfWallet.
+func QueryCheckpoints(c *gin.Context) ([]*checkpoint.Checkpoint, bool, error) {
+	cks := checkpointsOf(states.S.CurrentCheckpoints())
+	if len(cks) == 0 {
+		return nil, false, errors.New("no verified checkpoint available")
+	}
+	raw := c.Query("height")
+	if raw == "" || raw == cks[0].Height {
+		return cks, true, nil
+	}
+	height, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || height == 0 {
+		return nil, false, fmt.Errorf("invalid height: %q", raw)
+	}
+	exports, err := states.S.CheckpointAt(uint(height))
+	if err != nil {
+		return nil, false, err
+	}
+	return checkpointsOf(exports), false, nil
+}
+
+// GetCurrentBalanceOfWallet queries the current balance and verifies it against the commitment of the checkpoints,
+// which must be the current ones. Every committee indexer of the checkpoints is tried until one responds with a
+// verified proof, which is retained for the queries at the height later.
+func GetCurrentBalanceOfWallet(ctx context.Context, cks []*checkpoint.Checkpoint, tick, wallet string) (*apis.Brc20VerifiableCurrentBalanceOfWalletResponse, error) {
+	sources, point, err := newSources(cks)
 	if err != nil {
-		logs.Error.Printf("Create committee client failed: ck=%+v, tick=%s, wallet=%s, err=%v", ck, tick, wallet, err)
+		logs.Error.Ctx(ctx).With("tick", tick, "wallet", wallet).Printf("Create committee sources failed: %v", err)
 		return nil, err
 	}
+	return hedge(ctx, sources, func(ctx context.Context, src *source) (*apis.Brc20VerifiableCurrentBalanceOfWalletResponse, error) {
+		return getBalanceOfWallet(ctx, src.cl, src.ck, point, tick, wallet)
+	})
+}
 
-	balance, err := cl.CurrentBalanceOfWallet(context.Background(), tick, wallet)
+// GetCurrentBalanceOfPkscript is like GetCurrentBalanceOfWallet but queries by PkScript.
+func GetCurrentBalanceOfPkscript(ctx context.Context, cks []*checkpoint.Checkpoint, tick, pkscript string) (*apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse, error) {
+	sources, point, err := newSources(cks)
 	if err != nil {
-		logs.Error.Printf("Get balance of wallet error: ck=%+v, tick=%s, wallet=%s, err=%v", ck, tick, wallet, err)
+		logs.Error.Ctx(ctx).With("tick", tick, "pkscript", pkscript).Printf("Create committee sources failed: %v", err)
 		return nil, err
 	}
+	return hedge(ctx, sources, func(ctx context.Context, src *source) (*apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse, error) {
+		return getBalanceOfPkscript(ctx, src.cl, src.ck, point, tick, pkscript)
+	})
+}
+
+func commitmentPoint(ck *checkpoint.Checkpoint) (*verkle.Point, error) {
+	commitmentBytes, err := base64.StdEncoding.DecodeString(ck.Commitment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint commitment: commitment=%s, err=%v", ck.Commitment, err)
+	}
+	point := new(verkle.Point)
+	if err := point.SetBytes(commitmentBytes); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint commitment point: commitment=%s, err=%v", ck.Commitment, err)
+	}
+	return point, nil
+}
+
+func getBalanceOfWallet(
+	ctx context.Context,
+	cl committee.Client,
+	ck *checkpoint.Checkpoint,
+	point *verkle.Point,
+	tick, wallet string,
+) (_ *apis.Brc20VerifiableCurrentBalanceOfWalletResponse, err error) {
+	ctx, span := tracing.Start(
+		ctx,
+		"services.VerifyBalanceOfWallet",
+		attribute.String("url", ck.URL),
+		attribute.String("height", ck.Height),
+		attribute.String("commitment", ck.Commitment),
+		attribute.String("tick", tick),
+		attribute.String("wallet", wallet),
+	)
+	defer func() { tracing.End(span, err) }()
 
-	commitmentBytes, _ := base64.StdEncoding.DecodeString(ck.Commitment)
-	var point verkle.Point
-	_ = point.SetBytes(commitmentBytes)
+	errLog := logs.Error.Ctx(ctx).With("url", ck.URL, "height", ck.Height, "commitment", ck.Commitment, "tick", tick, "wallet", wallet)
+	balance, err := cl.CurrentBalanceOfWallet(ctx, tick, wallet)
+	if err != nil {
+		errLog.Printf("Get balance of wallet error: %v", err)
+		return nil, err
+	}
 
-	ok, err := apis.VerifyCurrentBalanceOfWallet(&point, tick, wallet, balance)
+	defer func() {
+		if err == nil {
+			retained.put(retainedKey{commitment: ck.Commitment, tick: tick, wallet: wallet}, balance)
+		}
+	}()
+	ok, err := apis.VerifyCurrentBalanceOfWallet(point, tick, wallet, balance)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), errMsgBalanceNotFound) {
 			return balance, nil
 		}
-		logs.Error.Printf("Verify balance of wallet error: ck=%+v, tick=%s, wallet=%s, balance=%+v, err=%v", ck, tick, wallet, balance, err)
+		errLog.Printf("Verify balance of wallet error: balance=%+v, err=%v", balance, err)
+		metrics.BalanceVerifyFailures.WithLabelValues("wallet").Inc()
 		return nil, err
 	}
 
 	if !ok {
-		logs.Error.Printf("Verify balance of wallet not OK: ck=%+v, tick=%s, wallet=%s, balance=%+v, err=%v", ck, tick, wallet, balance, err)
+		errLog.Printf("Verify balance of wallet not OK: balance=%+v", balance)
+		metrics.BalanceVerifyFailures.WithLabelValues("wallet").Inc()
 		return nil, fmt.Errorf("verify balance of wallet not OK")
 	}
 
 	return balance, nil
 }
 
-func GetCurrentBalanceOfPkscript(ck *checkpoint.Checkpoint, tick, pkscript string) (*apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse, error) {
-	cl, err := committee.New(ck.URL)
-	if err != nil {
-		logs.Error.Printf("Create committee client failed: ck=%+v, tick=%s, pkscript=%s, err=%v", ck, tick, pkscript, err)
-		return nil, err
-	}
+func getBalanceOfPkscript(
+	ctx context.Context,
+	cl committee.Client,
+	ck *checkpoint.Checkpoint,
+	point *verkle.Point,
+	tick, pkscript string,
+) (_ *apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse, err error) {
+	ctx, span := tracing.Start(
+		ctx,
+		"services.VerifyBalanceOfPkscript",
+		attribute.String("url", ck.URL),
+		attribute.String("height", ck.Height),
+		attribute.String("commitment", ck.Commitment),
+		attribute.String("tick", tick),
+		attribute.String("pkscript", pkscript),
+	)
+	defer func() { tracing.End(span, err) }()
 
-	balance, err := cl.CurrentBalanceOfPkscript(context.Background(), tick, pkscript)
+	errLog := logs.Error.Ctx(ctx).With("url", ck.URL, "height", ck.Height, "commitment", ck.Commitment, "tick", tick, "pkscript", pkscript)
+	balance, err := cl.CurrentBalanceOfPkscript(ctx, tick, pkscript)
 	if err != nil {
-		logs.Error.Printf("Get balance of PkScript error: ck=%+v, tick=%s, pkscript=%s, err=%v", ck, tick, pkscript, err)
+		errLog.Printf("Get balance of PkScript error: %v", err)
 		return nil, err
 	}
 
-	commitmentBytes, _ := base64.StdEncoding.DecodeString(ck.Commitment)
-	var point verkle.Point
-	_ = point.SetBytes(commitmentBytes)
-
-	ok, err := apis.VerifyCurrentBalanceOfPkscript(&point, tick, pkscript, balance)
+	defer func() {
+		if err == nil {
+			retained.put(retainedKey{commitment: ck.Commitment, tick: tick, pkscript: pkscript}, balance)
+		}
+	}()
+	ok, err := apis.VerifyCurrentBalanceOfPkscript(point, tick, pkscript, balance)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), errMsgBalanceNotFound) {
 			return balance, nil
 		}
-		logs.Error.Printf("Verify balance of PkScript error: ck=%+v, tick=%s, pkscript=%s, balance=%+v, err=%v", ck, tick, pkscript, balance, err)
+		errLog.Printf("Verify balance of PkScript error: balance=%+v, err=%v", balance, err)
+		metrics.BalanceVerifyFailures.WithLabelValues("pkscript").Inc()
 		return nil, err
 	}
 
 	if !ok {
-		logs.Error.Printf("Verify balance of PkScript not OK: ck=%+v, tick=%s, pkscript=%s, balance=%+v, err=%v", ck, tick, pkscript, balance, err)
+		errLog.Printf("Verify balance of PkScript not OK: balance=%+v", balance)
+		metrics.BalanceVerifyFailures.WithLabelValues("pkscript").Inc()
 		return nil, fmt.Errorf("verify balance of PkScript not OK")
 	}
 
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+const (
+	// DefaultMaxLagBlocks is the default maximal number of blocks the verified height could be behind the tip.
+	DefaultMaxLagBlocks = 3
+
+	// DefaultMaxSyncInterval is the default maximal time since the sync loop was last alive, long enough to cover a
+	// full checkpoint fetch and verification.
+	DefaultMaxSyncInterval = 10 * time.Minute
+
+	// DefaultRPCCheckTimeout is the timeout of probing the Bitcoin RPC.
+	DefaultRPCCheckTimeout = 5 * time.Second
+
+	// DefaultRPCCheckTTL is how long a probe of the Bitcoin RPC is reused, so the probes of the public readiness
+	// endpoint don't load the Bitcoin RPC.
+	DefaultRPCCheckTTL = 5 * time.Second
+)
+
+// rpcCheck is the last probe of the Bitcoin RPC, concurrent checks wait for the probe in progress.
+var rpcCheck struct {
+	at    time.Time
+	check *Check
+	tip   uint
+
+	sync.Mutex
+}
+
+// Check is the result of one health or readiness check.
+type Check struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+type HealthResponse struct {
+	OK     bool     `json:"ok"`
+	Checks []*Check `json:"checks"`
+}
+
+func newHealthResponse(checks ...*Check) *HealthResponse {
+	rsp := &HealthResponse{OK: true, Checks: checks}
+	for _, c := range checks {
+		rsp.OK = rsp.OK && c.OK
+	}
+	return rsp
+}
+
+func (r *HealthResponse) status() int {
+	if r.OK {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+// HandleHealthz reports if the process is alive and the sync loop is not stuck.
+func HandleHealthz(c *gin.Context) {
+	rsp := newHealthResponse(checkSync())
+	c.JSON(rsp.status(), rsp)
+}
+
+// HandleReadyz reports if the light indexer is serving verified data of the recent blocks.
+func HandleReadyz(c *gin.Context) {
+	rpc, tip := checkRPC(c.Request.Context())
+	rsp := newHealthResponse(
+		checkSync(),
+		checkVerified(),
+		checkLag(tip),
+		checkProviders(),
+		rpc,
+	)
+	c.JSON(rsp.status(), rsp)
+}
+
+func checkSync() *Check {
+	maxInterval := configs.C.Health.MaxSyncInterval.Duration
+	if maxInterval <= 0 {
+		maxInterval = DefaultMaxSyncInterval
+	}
+	since := time.Since(states.S.LastHeartbeat()).Truncate(time.Second)
+	return &Check{
+		Name:    "sync",
+		OK:      since <= maxInterval,
+		Message: fmt.Sprintf("last alive %s ago, max=%s", since, maxInterval),
+	}
+}
+
+func checkVerified() *Check {
+	status := states.Status(states.S.Status.Load())
+	return &Check{
+		Name:    "verified",
+		OK:      status == states.StatusVerified,
+		Message: status.String(),
+	}
+}
+
+func checkLag(tip uint) *Check {
+	c := &Check{Name: "lag"}
+	if tip == 0 {
+		c.Message = "unknown Bitcoin tip"
+		return c
+	}
+	maxLag := uint(DefaultMaxLagBlocks)
+	if m := configs.C.Health.MaxLagBlocks; m != nil {
+		maxLag = *m
+	}
+	var lag uint
+	if verified := states.S.CurrentHeight(); tip > verified {
+		lag = tip - verified
+	}
+	c.OK = lag <= maxLag
+	c.Message = fmt.Sprintf("%d blocks behind the tip, max=%d", lag, maxLag)
+	return c
+}
+
+func checkProviders() *Check {
+	healthy, total, minimal := states.S.Providers()
+	return &Check{
+		Name:    "providers",
+		OK:      healthy >= minimal,
+		Message: fmt.Sprintf("%d of %d providers healthy, min=%d", healthy, total, minimal),
+	}
+}
+
+// checkRPC probes the Bitcoin RPC and returns the tip, a probe within DefaultRPCCheckTTL is reused.
+func checkRPC(ctx context.Context) (*Check, uint) {
+	rpcCheck.Lock()
+	defer rpcCheck.Unlock()
+	if time.Since(rpcCheck.at) < DefaultRPCCheckTTL {
+		return rpcCheck.check, rpcCheck.tip
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultRPCCheckTimeout)
+	defer cancel()
+	tip, err := btcutl.BTC.GetLatestBlockHeight(ctx)
+	if err != nil {
+		rpcCheck.check, rpcCheck.tip = &Check{Name: "rpc", Message: err.Error()}, 0
+	} else {
+		rpcCheck.check, rpcCheck.tip = &Check{Name: "rpc", OK: true, Message: fmt.Sprintf("tip=%d", tip)}, tip
+	}
+	rpcCheck.at = time.Now()
+	return rpcCheck.check, rpcCheck.tip
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+func TestHealthChecks(t *testing.T) {
+	configs.C = new(configs.Config)
+	states.S = states.New(nil, "", nil, &configs.CheckpointExport{Checkpoint: &checkpoint.Checkpoint{Height: "100"}}, 1, 0, 0)
+
+	if c := checkSync(); !c.OK {
+		t.Fatal(c)
+	}
+	if c := checkVerified(); c.OK {
+		t.Fatal("expected not verified before the first update", c)
+	}
+	if c := checkProviders(); c.OK {
+		t.Fatal("expected no healthy providers before the first fetch", c)
+	}
+	if c := checkLag(DefaultMaxLagBlocks); !c.OK {
+		t.Fatal(c)
+	}
+	if c := checkLag(DefaultMaxLagBlocks + 1); c.OK {
+		t.Fatal("expected lagging", c)
+	}
+	if c := checkLag(0); c.OK {
+		t.Fatal("expected unknown tip to fail", c)
+	}
+	configs.C.Health.MaxLagBlocks = new(uint)
+	if c := checkLag(1); c.OK {
+		t.Fatal("expected zero max lag to require the tip", c)
+	}
+	configs.C.Health.MaxLagBlocks = nil
+
+	rpc := &countingRPC{tip: 100}
+	btcutl.BTC = btcutl.NewWithRPC(rpc)
+	for range 3 {
+		if c, tip := checkRPC(context.Background()); !c.OK || tip != 100 {
+			t.Fatal(c, tip)
+		}
+	}
+	if n := rpc.calls.Load(); n != 1 {
+		t.Fatalf("expected the probe reused: calls=%d", n)
+	}
+	if rsp := newHealthResponse(checkSync(), checkVerified()); rsp.OK || rsp.status() != http.StatusServiceUnavailable {
+		t.Fatal(rsp)
+	}
+
+	// The checks never wait for a verification holding the state lock.
+	states.S.Lock()
+	defer states.S.Unlock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newHealthResponse(checkSync(), checkVerified(), checkLag(DefaultMaxLagBlocks), checkProviders())
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("health checks blocked by the state lock")
+	}
+}
+
+// countingRPC reports the tip and counts the calls.
+type countingRPC struct {
+	tip   uint
+	calls atomic.Int32
+}
+
+func (r *countingRPC) Call(_ context.Context, _ string, _, out any) error {
+	r.calls.Add(1)
+	data, err := json.Marshal(map[string]any{"result": r.tip})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
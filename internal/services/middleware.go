@@ -0,0 +1,104 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/httputl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
+)
+
+const headerRequestID = "X-Request-Id"
+
+// RequestID attaches the request ID from the header, or a new one, to the request context and the response header.
+func RequestID(c *gin.Context) {
+	ctx := httputl.WithRequestID(c.Request.Context(), c.GetHeader(headerRequestID))
+	c.Request = c.Request.WithContext(ctx)
+	c.Header(headerRequestID, httputl.RequestID(ctx))
+	c.Next()
+}
+
+// Trace starts a span for every request, the spans of the handler are its children.
+func Trace(c *gin.Context) {
+	ctx, span := tracing.Start(
+		c.Request.Context(),
+		c.Request.Method+" "+c.FullPath(),
+		attribute.String("http.method", c.Request.Method),
+		attribute.String("http.target", c.Request.URL.RequestURI()),
+		attribute.String("request.id", httputl.RequestID(c.Request.Context())),
+	)
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.status_code", status))
+	var err error
+	if status >= http.StatusInternalServerError {
+		err = errors.New(http.StatusText(status))
+	}
+	tracing.End(span, err)
+}
+
+// AccessLog logs every request after it's served.
+func AccessLog(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	l := logs.Info
+	if status := c.Writer.Status(); status >= http.StatusInternalServerError {
+		l = logs.Error
+	} else if status >= http.StatusBadRequest {
+		l = logs.Warn
+	}
+	l.Ctx(c.Request.Context()).With(
+		"method", c.Request.Method,
+		"path", c.Request.URL.Path,
+		"status", c.Writer.Status(),
+		"latency", time.Since(start),
+		"client", c.ClientIP(),
+	).Print("HTTP request served")
+}
+
+// AdminAuth requires the bearer token in the Authorization header, empty token means no authentication.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func HandleGetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"level": logs.Level()})
+}
+
+func HandleSetLogLevel(c *gin.Context) {
+	var req struct {
+		Level string `json:"level"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := logs.SetLevel(req.Level); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logs.Info.With("level", logs.Level()).Print("Log level changed")
+	c.JSON(http.StatusOK, gin.H{"level": logs.Level()})
+}
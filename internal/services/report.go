@@ -0,0 +1,25 @@
+package services
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/reports"
+)
+
+var reportQueue *reports.Queue
+
+// InitReportQueue sets the queue of the checkpoints to upload to DA, whose status is served by the admin APIs.
+func InitReportQueue(q *reports.Queue) {
+	reportQueue = q
+}
+
+// HandleReportStatus returns the status of the DA report queue.
+func HandleReportStatus(c *gin.Context) {
+	if reportQueue == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "DA report disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, reportQueue.Status())
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/RiemaLabs/modular-indexer-committee/apis"
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+)
+
+// MaxRetainedBalances is the maximal number of verified balances retained for the queries at historical heights, the
+// oldest ones are evicted first.
+const MaxRetainedBalances = 100_000
+
+// ErrNotRetained is returned for a balance at a historical height that wasn't queried while the height was the current
+// one, since the committee indexers only prove balances against their current state.
+var ErrNotRetained = errors.New("balance not retained at the height")
+
+type retainedKey struct {
+	commitment string
+	tick       string
+	wallet     string
+	pkscript   string
+}
+
+// retainedBalances keeps the committee indexer responses whose proofs are verified, keyed by the commitment they're
+// verified against. A commitment identifies the whole state, so the responses stay valid for it forever and are shared
+// by all states in the process.
+type retainedBalances struct {
+	balances map[retainedKey]any
+	order    []retainedKey
+
+	sync.Mutex
+}
+
+var retained = &retainedBalances{balances: make(map[retainedKey]any)}
+
+func (r *retainedBalances) put(k retainedKey, rsp any) {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.balances[k]; !ok {
+		r.order = append(r.order, k)
+	}
+	r.balances[k] = rsp
+	for len(r.order) > MaxRetainedBalances {
+		delete(r.balances, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+func (r *retainedBalances) get(k retainedKey) (any, bool) {
+	r.Lock()
+	defer r.Unlock()
+	rsp, ok := r.balances[k]
+	return rsp, ok
+}
+
+// GetRetainedBalanceOfWallet returns the balance of the wallet verified against the commitment of the checkpoints when
+// they were the current ones, see GetCurrentBalanceOfWallet.
+func GetRetainedBalanceOfWallet(cks []*checkpoint.Checkpoint, tick, wallet string) (*apis.Brc20VerifiableCurrentBalanceOfWalletResponse, error) {
+	if len(cks) == 0 {
+		return nil, errors.New("no verified checkpoint available")
+	}
+	rsp, ok := retained.get(retainedKey{commitment: cks[0].Commitment, tick: tick, wallet: wallet})
+	if !ok {
+		return nil, fmt.Errorf("%w: height=%s, tick=%s, wallet=%s", ErrNotRetained, cks[0].Height, tick, wallet)
+	}
+	return rsp.(*apis.Brc20VerifiableCurrentBalanceOfWalletResponse), nil
+}
+
+// GetRetainedBalanceOfPkscript is like GetRetainedBalanceOfWallet but queries by PkScript.
+func GetRetainedBalanceOfPkscript(cks []*checkpoint.Checkpoint, tick, pkscript string) (*apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse, error) {
+	if len(cks) == 0 {
+		return nil, errors.New("no verified checkpoint available")
+	}
+	rsp, ok := retained.get(retainedKey{commitment: cks[0].Commitment, tick: tick, pkscript: pkscript})
+	if !ok {
+		return nil, fmt.Errorf("%w: height=%s, tick=%s, pkscript=%s", ErrNotRetained, cks[0].Height, tick, pkscript)
+	}
+	return rsp.(*apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse), nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/ethereum/go-verkle"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/committee"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+)
+
+// DefaultHedgeDelay is the delay before a pending query is also sent to the next committee indexer.
+const DefaultHedgeDelay = 500 * time.Millisecond
+
+// source is a committee indexer whose checkpoint matches the trusted commitment.
+type source struct {
+	ck *checkpoint.Checkpoint
+	cl committee.Client
+}
+
+// newSources creates the committee indexer clients of the checkpoints sharing the commitment of the first one, ordered
+// by the observed latency, and the commitment point to verify their proofs against.
+func newSources(cks []*checkpoint.Checkpoint) ([]*source, *verkle.Point, error) {
+	if len(cks) == 0 {
+		return nil, nil, errors.New("no verified checkpoint available")
+	}
+	point, err := commitmentPoint(cks[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sources []*source
+	seen := make(map[string]bool)
+	for _, ck := range cks {
+		if ck.Commitment != cks[0].Commitment || seen[ck.URL] {
+			continue
+		}
+		seen[ck.URL] = true
+		cl, err := committee.New(ck.URL)
+		if err != nil {
+			logs.Error.Printf("Create committee client failed: ck=%+v, err=%v", ck, err)
+			continue
+		}
+		sources = append(sources, &source{ck: ck, cl: cl})
+	}
+	if len(sources) == 0 {
+		return nil, nil, errors.New("no available committee indexer")
+	}
+
+	sortByLatency(sources)
+	return sources, point, nil
+}
+
+// sortByLatency orders the sources by the observed latency, the fastest first.
+func sortByLatency(sources []*source) {
+	slices.SortStableFunc(sources, func(a, b *source) int { return cmp.Compare(latencies.get(a.ck.URL), latencies.get(b.ck.URL)) })
+}
+
+// hedge sends the query to the sources one after another: the next source is tried when the previous one fails or
+// doesn't respond within DefaultHedgeDelay. The first verified result wins and cancels the others. A failure counts as
+// a latency of at least DefaultHedgeDelay, so the failing sources are queried after the healthy ones until they
+// recover.
+func hedge[T any](parent context.Context, sources []*source, query func(ctx context.Context, src *source) (T, error)) (T, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	type result struct {
+		v   T
+		err error
+	}
+	results := make(chan result, len(sources))
+	next := 0
+	launch := func() {
+		src := sources[next]
+		next++
+		go func() {
+			start := time.Now()
+			v, err := query(ctx, src)
+			switch elapsed := time.Since(start); {
+			case err == nil:
+				latencies.observe(src.ck.URL, elapsed)
+			case parent.Err() != nil:
+				// Canceled by the caller, nothing is learned.
+			case ctx.Err() != nil:
+				// Canceled by a faster source, it's at least that slow.
+				latencies.atLeast(src.ck.URL, elapsed)
+			default:
+				latencies.observe(src.ck.URL, max(elapsed, DefaultHedgeDelay))
+			}
+			results <- result{v, err}
+		}()
+	}
+
+	launch()
+	var errs []error
+	for pending := 1; pending > 0; {
+		// A new timer per round, resetting one that fired while a result was taken would launch the next source at once.
+		timer := time.NewTimer(DefaultHedgeDelay)
+		select {
+		case r := <-results:
+			timer.Stop()
+			pending--
+			if r.err == nil {
+				return r.v, nil
+			}
+			errs = append(errs, r.err)
+		case <-timer.C:
+		}
+		if next < len(sources) {
+			launch()
+			pending++
+		}
+	}
+
+	var zero T
+	return zero, errors.Join(errs...)
+}
+
+func checkpointsOf(exports []*configs.CheckpointExport) []*checkpoint.Checkpoint {
+	ret := make([]*checkpoint.Checkpoint, 0, len(exports))
+	for _, e := range exports {
+		ret = append(ret, e.Checkpoint)
+	}
+	return ret
+}
+
+// latencyTracker keeps the smoothed response latency of committee indexers, so the fastest is queried first. The
+// penalties of the failures decay with the later responses.
+type latencyTracker struct {
+	sync.Mutex
+	m map[string]time.Duration
+}
+
+var latencies = &latencyTracker{m: make(map[string]time.Duration)}
+
+func (t *latencyTracker) observe(url string, d time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+	if prev, ok := t.m[url]; ok {
+		d = (prev*7 + d) / 8
+	}
+	t.m[url] = d
+}
+
+// atLeast raises the latency to the lower bound if it's below.
+func (t *latencyTracker) atLeast(url string, d time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+	if d > t.m[url] {
+		t.m[url] = d
+	}
+}
+
+func (t *latencyTracker) get(url string) time.Duration {
+	t.Lock()
+	defer t.Unlock()
+	return t.m[url]
+}
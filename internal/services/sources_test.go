@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+)
+
+func TestHedge(t *testing.T) {
+	sources := []*source{
+		{ck: &checkpoint.Checkpoint{URL: "down"}},
+		{ck: &checkpoint.Checkpoint{URL: "slow"}},
+		{ck: &checkpoint.Checkpoint{URL: "fast"}},
+	}
+	v, err := hedge(context.Background(), sources, func(ctx context.Context, src *source) (string, error) {
+		switch src.ck.URL {
+		case "down":
+			return "", errors.New("down")
+		case "slow":
+			select {
+			case <-time.After(time.Minute):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		return src.ck.URL, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "fast" {
+		t.Fatal(v)
+	}
+
+	_, err = hedge(context.Background(), sources[:1], func(context.Context, *source) (string, error) {
+		return "", errors.New("down")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestHedge_AlwaysFailing(t *testing.T) {
+	sources := []*source{
+		{ck: &checkpoint.Checkpoint{URL: "always-failing"}},
+		{ck: &checkpoint.Checkpoint{URL: "healthy"}},
+	}
+	query := func(_ context.Context, src *source) (string, error) {
+		if src.ck.URL == "always-failing" {
+			return "", errors.New("down")
+		}
+		return src.ck.URL, nil
+	}
+	for i := 0; i < 3; i++ {
+		sortByLatency(sources)
+		if v, err := hedge(context.Background(), sources, query); err != nil || v != "healthy" {
+			t.Fatal(v, err)
+		}
+	}
+	if sources[0].ck.URL != "healthy" {
+		t.Fatal("expected the failing source queried last")
+	}
+	if d := latencies.get("always-failing"); d < DefaultHedgeDelay {
+		t.Fatal("expected the failure penalized", d)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+// DefaultHeartbeat is the interval of keep-alive comments sent on idle event streams.
+const DefaultHeartbeat = 15 * time.Second
+
+// HandleStreamEvents streams state events as Server-Sent Events, the `types` query filters the event types with a
+// comma-separated list.
+func HandleStreamEvents(c *gin.Context) {
+	var types []states.EventType
+	if raw := c.Query("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			types = append(types, states.EventType(strings.TrimSpace(t)))
+		}
+	}
+
+	events, unsubscribe := states.S.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// The current status goes first, so clients don't have to poll it after connecting.
+	status := states.Status(states.S.Status.Load())
+	c.SSEvent(string(states.EventStatus), &states.Event{
+		Type:   states.EventStatus,
+		Time:   time.Now(),
+		Status: status.String(),
+		Height: states.S.CurrentHeight(),
+	})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(DefaultHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			if len(types) == 0 || slices.Contains(types, e.Type) {
+				c.SSEvent(string(e.Type), e)
+			}
+			return true
+		}
+	})
+}
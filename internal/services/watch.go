@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/httputl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+const (
+	// MaxWatches is the maximum number of watches on the watch list.
+	MaxWatches = 10000
+
+	// DefaultWebhookRetries is the number of attempts to deliver a balance change to a webhook.
+	DefaultWebhookRetries = 3
+
+	// DefaultWebhookTimeout is the timeout of each attempt to deliver a balance change to a webhook.
+	DefaultWebhookTimeout = 10 * time.Second
+
+	// DefaultWebhookWorkers is the number of workers delivering the balance changes to the webhooks.
+	DefaultWebhookWorkers = 4
+
+	// DefaultWebhookQueueSize is the number of balance changes waiting for the webhook workers, the others are dropped.
+	DefaultWebhookQueueSize = 1024
+)
+
+// Watch is a (tick, wallet) pair whose verified balance changes are notified.
+type Watch struct {
+	ID      string `json:"id"`
+	Tick    string `json:"tick"`
+	Wallet  string `json:"wallet"`
+	Webhook string `json:"webhook,omitempty"`
+
+	// The last verified balance and its height, empty until the first check.
+	Height  uint            `json:"height,omitempty"`
+	Balance *states.Balance `json:"balance,omitempty"`
+}
+
+// WatchList is the persisted watch list, the added and removed watches are saved to the file at once, and the checked
+// balances once a check is done.
+type WatchList struct {
+	path    string
+	watches []*Watch
+
+	// dirty reports whether there are checked balances not saved yet.
+	dirty bool
+
+	// allow is the loopback, link-local and private networks the webhooks may target.
+	allow         []netip.Prefix
+	client        *http.Client
+	notifications chan *notification
+
+	sync.Mutex
+}
+
+type notification struct {
+	webhook string
+	event   *states.Event
+}
+
+var W *WatchList
+
+// ReadWatchList reads the watch list, whose webhooks may target the networks of the allow list apart from the public
+// ones.
+func ReadWatchList(path string, allow []netip.Prefix) (*WatchList, error) {
+	l := &WatchList{path: path, allow: allow, notifications: make(chan *notification, DefaultWebhookQueueSize)}
+	l.client = l.webhookClient()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return l, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &l.watches); err != nil {
+		return nil, fmt.Errorf("invalid watch list: path=%s, err=%v", path, err)
+	}
+	return l, nil
+}
+
+func InitWatchList(path string, allow []netip.Prefix) error {
+	l, err := ReadWatchList(path, allow)
+	if err != nil {
+		return err
+	}
+	W = l
+	return nil
+}
+
+func (l *WatchList) List() []*Watch {
+	l.Lock()
+	defer l.Unlock()
+	ret := make([]*Watch, 0, len(l.watches))
+	for _, w := range l.watches {
+		c := *w
+		ret = append(ret, &c)
+	}
+	return ret
+}
+
+func (l *WatchList) Add(tick, wallet, webhook string) (*Watch, error) {
+	if tick == "" || wallet == "" {
+		return nil, errors.New("tick and wallet are required")
+	}
+	if webhook != "" {
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid webhook: %q", webhook)
+		}
+		if err := l.checkWebhookHost(u.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if len(l.watches) >= MaxWatches {
+		return nil, fmt.Errorf("too many watches: max=%d", MaxWatches)
+	}
+	w := &Watch{ID: uuid.NewString(), Tick: tick, Wallet: wallet, Webhook: webhook}
+	l.watches = append(l.watches, w)
+	if err := l.save(); err != nil {
+		l.watches = l.watches[:len(l.watches)-1]
+		return nil, err
+	}
+	c := *w
+	return &c, nil
+}
+
+func (l *WatchList) Remove(id string) (bool, error) {
+	l.Lock()
+	defer l.Unlock()
+	i := slices.IndexFunc(l.watches, func(w *Watch) bool { return w.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	l.watches = slices.Delete(l.watches, i, i+1)
+	return true, l.save()
+}
+
+// save writes the watch list to a temporary file and renames it, so the file is never half-written.
+func (l *WatchList) save() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(l.watches, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write watch list error: %v", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// flush saves the checked balances if there are any not saved yet.
+func (l *WatchList) flush() {
+	l.Lock()
+	defer l.Unlock()
+	if !l.dirty {
+		return
+	}
+	if err := l.save(); err != nil {
+		logs.Error.Printf("Save watch list error: %v", err)
+	}
+}
+
+// update records the verified balance of the watch and returns the previous one if it changes.
+func (l *WatchList) update(id string, height uint, balance *states.Balance) (prev *states.Balance, changed bool) {
+	l.Lock()
+	defer l.Unlock()
+	i := slices.IndexFunc(l.watches, func(w *Watch) bool { return w.ID == id })
+	if i < 0 {
+		return nil, false
+	}
+	w := l.watches[i]
+	if w.Height >= height {
+		return nil, false
+	}
+	prev = w.Balance
+	changed = prev != nil && *prev != *balance
+	w.Height = height
+	w.Balance = balance
+	l.dirty = true
+	return prev, changed
+}
+
+// Check re-queries and verifies the balances of all watches against the current checkpoints, balance changes are
+// published to the state event stream and queued for the webhooks. The balances are saved once all are checked.
+func (l *WatchList) Check(ctx context.Context) {
+	watches := l.List()
+	if len(watches) == 0 {
+		return
+	}
+	cks := checkpointsOf(states.S.CurrentCheckpoints())
+	if len(cks) == 0 {
+		logs.Error.Ctx(ctx).Print("Check watch list error: no verified checkpoint available")
+		return
+	}
+	h, err := strconv.ParseUint(cks[0].Height, 10, 64)
+	if err != nil {
+		logs.Error.Ctx(ctx).With("height", cks[0].Height).Printf("Check watch list error: %v", err)
+		return
+	}
+	height := uint(h)
+
+	sem := make(chan struct{}, DefaultBatchConcurrency)
+	var wg sync.WaitGroup
+	for _, w := range watches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			rsp, err := GetCurrentBalanceOfWallet(ctx, cks, w.Tick, w.Wallet)
+			if err != nil {
+				logs.Error.Ctx(ctx).With("height", height, "watch", w.ID, "tick", w.Tick, "wallet", w.Wallet).Printf("Check watch error: %v", err)
+				return
+			}
+			balance := &states.Balance{AvailableBalance: "0", OverallBalance: "0"}
+			if r := rsp.Result; r != nil {
+				balance.AvailableBalance = r.AvailableBalance
+				balance.OverallBalance = r.OverallBalance
+			}
+
+			prev, changed := l.update(w.ID, height, balance)
+			if !changed {
+				return
+			}
+			e := &states.Event{
+				Type:            states.EventBalance,
+				Height:          height,
+				Hash:            cks[0].Hash,
+				Commitment:      cks[0].Commitment,
+				Tick:            w.Tick,
+				Wallet:          w.Wallet,
+				Balance:         balance,
+				PreviousBalance: prev,
+			}
+			states.S.Publish(e)
+			if w.Webhook != "" {
+				l.notify(ctx, w.Webhook, e)
+			}
+		}()
+	}
+	wg.Wait()
+	l.flush()
+}
+
+// notify queues the balance change for the webhook workers, it's dropped if the queue is full.
+func (l *WatchList) notify(ctx context.Context, webhook string, e *states.Event) {
+	select {
+	case l.notifications <- &notification{webhook: webhook, event: e}:
+	default:
+		logs.Error.Ctx(ctx).With("webhook", webhook, "height", e.Height).Print("Webhook queue is full, balance change dropped")
+	}
+}
+
+// runNotifier delivers the queued balance changes to their webhooks until the context is done.
+func (l *WatchList) runNotifier(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-l.notifications:
+			l.notifyWebhook(ctx, n.webhook, n.event)
+		}
+	}
+}
+
+func (l *WatchList) notifyWebhook(ctx context.Context, webhook string, e *states.Event) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		logs.Error.Ctx(ctx).With("webhook", webhook).Printf("Invalid webhook: %v", err)
+		return
+	}
+	for i := 0; i < DefaultWebhookRetries; i++ {
+		if err = httputl.PostJSONWith(ctx, l.client, u, e, nil); err == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(i+1) * time.Second):
+		}
+	}
+	logs.Error.Ctx(ctx).With("webhook", webhook).Printf("Notify webhook error: %v", err)
+}
+
+// webhookClient returns the client of the webhooks, which refuses to connect to the addresses checkWebhookAddr rejects,
+// so a host name resolving to them is caught as well.
+func (l *WatchList) webhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: DefaultWebhookTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return l.checkWebhookAddr(addr.Addr())
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect to the webhook on our behalf, bypassing the check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: DefaultWebhookTimeout, Transport: transport}
+}
+
+// checkWebhookHost rejects the webhook host on the networks checkWebhookAddr rejects, host names are checked when
+// connecting since they may resolve to other addresses later.
+func (l *WatchList) checkWebhookHost(host string) error {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return l.checkWebhookAddr(netip.IPv6Loopback())
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return l.checkWebhookAddr(addr)
+	}
+	return nil
+}
+
+// checkWebhookAddr rejects the loopback, link-local, private and unspecified addresses out of the allow list, so the
+// webhooks couldn't reach the services only exposed to the host or its network.
+func (l *WatchList) checkWebhookAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if !addr.IsLoopback() && !addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsPrivate() &&
+		!addr.IsUnspecified() {
+		return nil
+	}
+	for _, p := range l.allow {
+		if p.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook address not allowed, add it to admin.webhookAllowList if it's intended: addr=%s", addr)
+}
+
+// RunWatcher checks the watch list whenever a new verified checkpoint is accepted, until the context is done or the
+// state events are closed. The checks and the webhooks run apart from reading the state events, so they never hold the
+// events back, and the checks pending at once are merged into one against the latest checkpoints.
+func RunWatcher(ctx context.Context, l *WatchList) {
+	events, unsubscribe := states.S.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	checks := make(chan struct{}, 1)
+	wg.Add(1 + DefaultWebhookWorkers)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-checks:
+				l.Check(httputl.WithRequestID(ctx, ""))
+			}
+		}
+	}()
+	for i := 0; i < DefaultWebhookWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			l.runNotifier(ctx)
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type == states.EventCheckpoint {
+				select {
+				case checks <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func HandleListWatches(c *gin.Context) {
+	c.JSON(http.StatusOK, W.List())
+}
+
+func HandleAddWatch(c *gin.Context) {
+	var req struct {
+		Tick    string `json:"tick"`
+		Wallet  string `json:"wallet"`
+		Webhook string `json:"webhook"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	w, err := W.Add(req.Tick, req.Wallet, req.Webhook)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+func HandleRemoveWatch(c *gin.Context) {
+	found, err := W.Remove(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "watch not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
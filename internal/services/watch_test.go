@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+func TestWatchList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlist.json")
+	l, err := ReadWatchList(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Add("ordi", "", ""); err == nil {
+		t.Fatal("expected error")
+	}
+	w, err := l.Add("ordi", "bc1qhuv3dhpnm0wktasd3v0kt6e4aqfqsd0uhfdu7d", "https://example.com/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, changed := l.update(w.ID, 100, &states.Balance{AvailableBalance: "1", OverallBalance: "1"}); changed {
+		t.Fatal("the first balance is the baseline")
+	}
+	if _, changed := l.update(w.ID, 101, &states.Balance{AvailableBalance: "1", OverallBalance: "1"}); changed {
+		t.Fatal("unchanged balance")
+	}
+	prev, changed := l.update(w.ID, 102, &states.Balance{AvailableBalance: "0", OverallBalance: "1"})
+	if !changed || prev.AvailableBalance != "1" {
+		t.Fatal(prev, changed)
+	}
+
+	if unsaved, err := ReadWatchList(path, nil); err != nil || unsaved.List()[0].Height != 0 {
+		t.Fatal("checked balances saved before the check is done", err)
+	}
+	l.flush()
+
+	reloaded, err := ReadWatchList(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watches := reloaded.List()
+	if len(watches) != 1 || watches[0].Height != 102 || watches[0].Balance.AvailableBalance != "0" {
+		t.Fatal(watches)
+	}
+
+	if found, err := reloaded.Remove(w.ID); err != nil || !found {
+		t.Fatal(found, err)
+	}
+	if found, _ := reloaded.Remove(w.ID); found {
+		t.Fatal("removed twice")
+	}
+}
+
+func TestWatchList_Webhook(t *testing.T) {
+	var received atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	const wallet = "bc1qhuv3dhpnm0wktasd3v0kt6e4aqfqsd0uhfdu7d"
+	l, err := ReadWatchList("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, webhook := range []string{
+		srv.URL,
+		"http://localhost:8081/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1/hook",
+		"http://0.0.0.0/hook",
+		"http:///hook",
+	} {
+		if _, err := l.Add("ordi", wallet, webhook); err == nil {
+			t.Fatal("expected the webhook rejected", webhook)
+		}
+	}
+	if _, err := l.Add("ordi", wallet, "https://example.com/hook"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Host names are checked when connecting, and the retries stop once the context is done.
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &states.Event{Type: states.EventBalance, Height: 100}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	l.notifyWebhook(ctx, "http://localhost:"+u.Port(), e)
+	if d := time.Since(start); d > time.Second {
+		t.Fatal("expected the retries canceled", d)
+	}
+	if received.Load() != 0 {
+		t.Fatal("expected the loopback webhook not notified")
+	}
+
+	allowed, err := ReadWatchList("", []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := allowed.Add("ordi", wallet, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	allowed.notifyWebhook(context.Background(), srv.URL, e)
+	if n := received.Load(); n != 1 {
+		t.Fatal("expected the allowed webhook notified", n)
+	}
+}
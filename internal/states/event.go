@@ -0,0 +1,123 @@
+package states
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+)
+
+// DefaultEventBuffer is the number of events buffered for each subscriber, events are dropped for slow subscribers
+// rather than blocking the verification.
+const DefaultEventBuffer = 64
+
+type EventType string
+
+const (
+	// EventStatus is published when the verification status changes.
+	EventStatus EventType = "status"
+
+	// EventCheckpoint is published when a new verified checkpoint is accepted.
+	EventCheckpoint EventType = "checkpoint"
+
+	// EventConflict is published when providers report inconsistent checkpoints.
+	EventConflict EventType = "conflict"
+
+	// EventDenial is published when a provider is added to the deny list.
+	EventDenial EventType = "denial"
+
+	// EventBalance is published when a verified balance on the watch list changes.
+	EventBalance EventType = "balance"
+)
+
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+
+	Status      string   `json:"status,omitempty"`
+	Height      uint     `json:"height,omitempty"`
+	Hash        string   `json:"hash,omitempty"`
+	Commitment  string   `json:"commitment,omitempty"`
+	Commitments []string `json:"commitments,omitempty"`
+
+	SourceS3 *configs.SourceS3 `json:"sourceS3,omitempty"`
+	SourceDA *configs.SourceDA `json:"sourceDa,omitempty"`
+
+	// Entry is the deny list entry of a denial, nil if it failed to append.
+	Entry *configs.DenyList `json:"entry,omitempty"`
+
+	Tick            string   `json:"tick,omitempty"`
+	Wallet          string   `json:"wallet,omitempty"`
+	Balance         *Balance `json:"balance,omitempty"`
+	PreviousBalance *Balance `json:"previousBalance,omitempty"`
+}
+
+type Balance struct {
+	AvailableBalance string `json:"availableBalance"`
+	OverallBalance   string `json:"overallBalance"`
+}
+
+type broker struct {
+	sync.Mutex
+	subs   map[chan *Event]struct{}
+	closed bool
+}
+
+// Subscribe returns the channel of state events and the function to unsubscribe.
+func (s *State) Subscribe() (<-chan *Event, func()) {
+	ch := make(chan *Event, DefaultEventBuffer)
+	s.events.Lock()
+	defer s.events.Unlock()
+	if s.events.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if s.events.subs == nil {
+		s.events.subs = make(map[chan *Event]struct{})
+	}
+	s.events.subs[ch] = struct{}{}
+
+	return ch, func() {
+		s.events.Lock()
+		defer s.events.Unlock()
+		if _, ok := s.events.subs[ch]; ok {
+			delete(s.events.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes the channels of all subscribers, so the event streams end, and later subscriptions get closed
+// channels.
+func (s *State) Close() {
+	s.events.Lock()
+	defer s.events.Unlock()
+	s.events.closed = true
+	for ch := range s.events.subs {
+		close(ch)
+	}
+	s.events.subs = nil
+}
+
+// Publish sends the event to all subscribers.
+func (s *State) Publish(e *Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	s.events.Lock()
+	defer s.events.Unlock()
+	for ch := range s.events.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// setStatus stores the status and publishes an event if it changes.
+func (s *State) setStatus(status Status, height uint, hash string) {
+	s.progress.SetStatus(int(status))
+	if old := s.Status.Swap(int64(status)); old != int64(status) {
+		s.Publish(&Event{Type: EventStatus, Status: status.String(), Height: height, Hash: hash})
+	}
+}
@@ -1,8 +1,8 @@
 package states
 
 import (
+	"cmp"
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"slices"
@@ -13,14 +13,15 @@ import (
 
 	"github.com/RiemaLabs/modular-indexer-committee/apis"
 	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
-	"github.com/RiemaLabs/modular-indexer-committee/ord/getter"
-	"github.com/ethereum/go-verkle"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
 	"github.com/RiemaLabs/modular-indexer-light/internal/clients/committee"
-	"github.com/RiemaLabs/modular-indexer-light/internal/clients/ordi"
 	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
 	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
+	"github.com/RiemaLabs/modular-indexer-light/internal/tracing"
 )
 
 // TODO: Medium. Uniform the error report.
@@ -46,209 +47,220 @@ func (s Status) String() string {
 	}
 }
 
+// DefaultHistorySize is the default number of verified checkpoints retained for historical queries.
+const DefaultHistorySize = 1024
+
 type State struct {
 	Status atomic.Int64
 
+	// The Bitcoin RPC client to sync the tip and verify the Ordinals transfers.
+	btc *btcutl.Client
+
 	denyListPath string
 
-	providers []checkpoints.CheckpointProvider
+	// The providers and the minimal number of checkpoints, swapped as a whole on reload without the state lock.
+	providers atomic.Pointer[providerSet]
 
 	// The consistent check point at the current height - 1.
 	lastCheckpoint *configs.CheckpointExport
 
-	// The checkpoints got from providers at the current height.
+	// The checkpoints got from providers at the current height, and the height stored after they're swapped in.
 	currentCheckpoints []*configs.CheckpointExport
-
-	// The number of effective providers should exceed the minimum required.
-	minimalCheckpoint int
+	height             atomic.Uint64
 
 	// timeout for request checkpoint.
 	timeout time.Duration
 
+	// The verified checkpoints of the recent heights, ordered by height ascending. All checkpoints of one height share
+	// the same commitment but may come from different providers.
+	history     [][]*configs.CheckpointExport
+	historySize int
+
+	events broker
+
+	// The last time the sync loop made progress, and the number of providers answered the last checkpoint fetch.
+	heartbeat atomic.Int64
+	healthy   atomic.Int64
+
+	// The gauges of the verification progress, the default registry ones unless SetMetrics is called.
+	progress *metrics.Verification
+
 	sync.RWMutex
 }
 
+type providerSet struct {
+	providers []checkpoints.CheckpointProvider
+
+	// The number of effective providers should exceed the minimum required.
+	minimalCheckpoint int
+}
+
 var S *State
 
 func New(
+	btc *btcutl.Client,
 	denyListPath string,
 	providers []checkpoints.CheckpointProvider,
 	lastCheckpoint *configs.CheckpointExport,
 	minimalCheckpoint int,
 	fetchTimeout time.Duration,
+	historySize int,
 ) *State {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	if fetchTimeout <= 0 {
+		fetchTimeout = DefaultFetchTimeout
+	}
 	s := &State{
-		denyListPath:      denyListPath,
-		providers:         providers,
-		lastCheckpoint:    lastCheckpoint,
-		minimalCheckpoint: minimalCheckpoint,
-		timeout:           fetchTimeout,
+		btc:            btc,
+		denyListPath:   denyListPath,
+		lastCheckpoint: lastCheckpoint,
+		timeout:        fetchTimeout,
+		historySize:    historySize,
+		progress:       metrics.Default,
 	}
+	s.providers.Store(&providerSet{providers: providers, minimalCheckpoint: minimalCheckpoint})
+	s.remember([]*configs.CheckpointExport{lastCheckpoint})
 	s.Status.Store(int64(StatusVerifying))
+	s.Heartbeat()
 	return s
 }
 
 func Init(
+	btc *btcutl.Client,
 	denyListPath string,
 	providers []checkpoints.CheckpointProvider,
 	lastCheckpoint *configs.CheckpointExport,
 	minimalCheckpoint int,
 	fetchTimeout time.Duration,
+	historySize int,
 ) {
-	S = New(denyListPath, providers, lastCheckpoint, minimalCheckpoint, fetchTimeout)
+	S = New(btc, denyListPath, providers, lastCheckpoint, minimalCheckpoint, fetchTimeout, historySize)
 }
 
+// SetMetrics makes the state report its verification progress to the gauges, it must be called before syncing.
+func (s *State) SetMetrics(v *metrics.Verification) {
+	s.progress = v
+}
+
+// CurrentHeight returns the height of the current checkpoints, zero before the first verification. It's read without the
+// state lock, so it never waits for a verification in progress.
 func (s *State) CurrentHeight() uint {
-	ck := s.CurrentFirstCheckpoint()
-	if ck == nil {
-		return 0
-	}
-	h, err := strconv.ParseUint(ck.Checkpoint.Height, 10, 64)
-	if err != nil {
-		logs.Error.Printf("parse checkpoint height failed: %v", err)
-	}
-	return uint(h)
+	return uint(s.height.Load())
+}
+
+// setCurrent swaps in the verified checkpoints at the height, then publishes the height and the verified status.
+func (s *State) setCurrent(cks []*configs.CheckpointExport, height uint, hash string) {
+	s.currentCheckpoints = cks
+	s.lastCheckpoint = cks[0]
+	s.remember(cks)
+	s.height.Store(uint64(height))
+	s.progress.SetVerifiedHeight(height)
+	s.setStatus(StatusVerified, height, hash)
 }
 
-func (s *State) UpdateCheckpoints(height uint, hash string) error {
+// UpdateCheckpoints fetches and verifies the checkpoints at the height, canceling the context aborts the checkpoint
+// fetches and the verification RPC calls.
+func (s *State) UpdateCheckpoints(ctx context.Context, height uint, hash string) (err error) {
 	s.Lock()
 	defer s.Unlock()
 
-	s.Status.Store(int64(StatusVerifying))
+	ctx, span := tracing.Start(
+		ctx,
+		"states.UpdateCheckpoints",
+		attribute.Int64("height", int64(height)),
+		attribute.String("hash", hash),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	s.setStatus(StatusVerifying, height, hash)
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	// A reload during the verification takes effect from the next height.
+	ps := s.providers.Load()
+	fetchCtx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
-	cps, err := checkpoints.GetCheckpoints(ctx, s.providers, height, hash)
+	cps, err := checkpoints.GetCheckpoints(fetchCtx, ps.providers, height, hash)
+	s.healthy.Store(int64(len(cps)))
 	if err != nil {
 		return err
 	}
-	if l := len(cps); l < s.minimalCheckpoint {
-		return fmt.Errorf("not enough checkpoints fetched: expected=%d, actual=%d", s.minimalCheckpoint, l)
+	if l := len(cps); l < ps.minimalCheckpoint {
+		return fmt.Errorf("not enough checkpoints fetched: expected=%d, actual=%d", ps.minimalCheckpoint, l)
 	}
 
 	if checkpoints.Inconsistent(cps) {
-		logs.Warn.Printf("Inconsistent checkpoints at: height=%d, hash=%s", height, hash)
-		s.Status.Store(int64(StatusUnverified))
+		logs.Warn.With("height", height, "hash", hash).Print("Inconsistent checkpoints")
+		s.setStatus(StatusUnverified, height, hash)
 
 		aggregates := make(map[string]*configs.CheckpointExport)
 		for _, ck := range cps {
 			aggregates[ck.Checkpoint.Commitment] = ck
 		}
-
-		type succCommit struct {
-			commitment  string
-			transferLen int
+		commitments := make([]string, 0, len(aggregates))
+		for commit := range aggregates {
+			commitments = append(commitments, commit)
 		}
+		slices.Sort(commitments)
+		s.Publish(&Event{Type: EventConflict, Height: height, Hash: hash, Commitments: commitments})
+		metrics.ConflictsDetected.Inc()
 
+		prevCommitment := s.lastCheckpoint.Checkpoint.Commitment
 		succCommits := make(chan succCommit, len(aggregates))
-		var wg sync.WaitGroup
+		var (
+			wg       sync.WaitGroup
+			proofsMu sync.Mutex
+			proofs   = make(map[string]*configs.FraudProof)
+		)
 		for commit, ck := range aggregates {
 			wg.Add(1)
 			go func(checkpointCommit string, ck *checkpoint.Checkpoint) {
 				defer wg.Done()
 
+				fields := []any{"height", height, "hash", hash, "commitment", checkpointCommit, "provider", ck.Name, "url", ck.URL}
+				errLog := logs.Error.With(fields...)
+
 				committeeCl, err := committee.New(ck.URL)
 				if err != nil {
-					logs.Error.Printf(
-						"Failed to create committee indexer client: commit=%s, name=%s, url=%s, err=%v",
-						checkpointCommit,
-						ck.Name,
-						ck.URL,
-						err,
-					)
+					errLog.Printf("Failed to create committee indexer client: %v", err)
 					return
 				}
-				stateProof, err := committeeCl.LatestStateProof(context.Background())
+				stateProof, err := latestStateProof(ctx, committeeCl, ck)
 				if err != nil {
-					logs.Error.Printf(
-						"Failed to get latest state proof from the committee indexer: commit=%s, name=%s, url=%s, err=%v",
-						checkpointCommit,
-						ck.Name,
-						ck.URL,
-						err,
-					)
+					errLog.Printf("Failed to get latest state proof from the committee indexer: %v", err)
 					return
 				}
 				if errMsg := stateProof.Error; errMsg != nil {
-					logs.Error.Printf(
-						"Non-nil error message from the latest state proof: commit=%s, name=%s, url=%s, errMsg=%s",
-						checkpointCommit,
-						ck.Name,
-						ck.URL,
-						*errMsg,
-					)
-					return
-				}
-
-				var ordTransfers []getter.OrdTransfer
-				for _, t := range stateProof.Result.OrdTransfers {
-					contentBytes, err := base64.StdEncoding.DecodeString(t.Content)
-					if err != nil {
-						logs.Error.Printf(
-							"Invalid Ordinals transfer content: commit=%s, name=%s, url=%s, err=%v",
-							checkpointCommit,
-							ck.Name,
-							ck.URL,
-							err,
-						)
-						return
-					}
-					ordTransfers = append(ordTransfers, getter.OrdTransfer{
-						ID:            t.ID,
-						InscriptionID: t.InscriptionID,
-						OldSatpoint:   t.OldSatpoint,
-						NewSatpoint:   t.NewSatpoint,
-						NewPkscript:   t.NewPkscript,
-						NewWallet:     t.NewWallet,
-						SentAsFee:     t.SentAsFee,
-						Content:       contentBytes,
-						ContentType:   t.ContentType,
-					})
-				}
-
-				curHeight, _ := strconv.ParseInt(ck.Height, 10, 64)
-				if err := ordi.VerifyOrdTransfer(ordTransfers, uint(curHeight)); err != nil {
-					logs.Error.Printf("Ordinals transfers verification error: err=%v", err)
+					errLog.Printf("Non-nil error message from the latest state proof: %s", *errMsg)
 					return
 				}
 
-				preCheckpoint := s.lastCheckpoint
-				prePointByte, err := base64.StdEncoding.DecodeString(preCheckpoint.Checkpoint.Commitment)
-				if err != nil {
+				transferLen, err := checkpoints.VerifyStateProof(ctx, s.btc, prevCommitment, height, checkpointCommit, stateProof)
+				var proofErr *checkpoints.ProofError
+				if errors.As(err, &proofErr) {
+					logs.Warn.With(fields...).Printf("State proof failed the verification: %v", err)
+					proof := checkpoints.NewFraudProof(height, hash, prevCommitment, checkpointCommit, ck.Name, ck.URL, stateProof, proofErr)
+					proofsMu.Lock()
+					proofs[checkpointCommit] = proof
+					proofsMu.Unlock()
 					return
 				}
-				prePoint := new(verkle.Point)
-				if err := prePoint.SetBytes(prePointByte); err != nil {
-					return
-				}
-
-				node, err := apis.GeneratePostRoot(prePoint, height, stateProof)
 				if err != nil {
-					logs.Error.Printf("generate post root error: %v", err)
-					return
-				}
-				if node == nil {
-					return
-				}
-
-				postBytes := node.Commit().Bytes()
-				calCommit := base64.StdEncoding.EncodeToString(postBytes[:])
-				if calCommit != checkpointCommit {
-					logs.Warn.Printf(
-						"inconsistent commits: calCommit=%s, checkpointCommit=%s",
-						calCommit,
-						checkpointCommit,
-					)
+					errLog.Printf("State proof verification error: %v", err)
 					return
 				}
 
 				succCommits <- succCommit{
 					commitment:  checkpointCommit,
-					transferLen: len(ordTransfers),
+					transferLen: transferLen,
 				}
 			}(commit, ck.Checkpoint)
 		}
 		wg.Wait()
+		if err := ctx.Err(); err != nil {
+			// Verification is aborted, any failure above is not an evidence of fraud.
+			return err
+		}
 
 		close(succCommits)
 		var succVerify []succCommit
@@ -259,39 +271,111 @@ func (s *State) UpdateCheckpoints(height uint, hash string) error {
 			return errors.New("all cps verify failed")
 		}
 
-		maxTransfer := succVerify[0].transferLen
-		champion := 0
-		var seemRight []string
-		for i := 1; i < len(succVerify); i++ {
-			seemRight = append(seemRight, succVerify[i].commitment)
-			if succVerify[i].transferLen > maxTransfer {
-				maxTransfer = succVerify[i].transferLen
-				champion = i
-			}
-		}
-		trustCommitment := succVerify[champion].commitment
+		trustCommitment, seemRight := verifiedCommitments(succVerify)
 
-		s.currentCheckpoints = []*configs.CheckpointExport{aggregates[trustCommitment]}
-		s.lastCheckpoint = s.currentCheckpoints[0]
-		s.Status.Store(int64(StatusVerified))
+		// Keep every checkpoint agreeing on the trusted commitment, so queries could fall back among their providers.
+		s.setCurrent(slices.DeleteFunc(slices.Clone(cps), func(ck *configs.CheckpointExport) bool {
+			return ck.Checkpoint.Commitment != trustCommitment
+		}), height, hash)
+		s.Publish(&Event{Type: EventCheckpoint, Height: height, Hash: hash, Commitment: trustCommitment})
 
+		// Only the commitments failing the verification are denied, with the fraud proof if any.
 		for _, ck := range cps {
 			if !slices.Contains(seemRight, ck.Checkpoint.Commitment) && s.denyListPath != "" {
-				checkpoints.Deny(s.denyListPath, aggregates[trustCommitment], ck)
+				entry := checkpoints.Deny(s.denyListPath, aggregates[trustCommitment], ck, proofs[ck.Checkpoint.Commitment])
+				s.Publish(&Event{
+					Type:       EventDenial,
+					Height:     height,
+					Hash:       hash,
+					Commitment: ck.Checkpoint.Commitment,
+					SourceS3:   ck.SourceS3,
+					SourceDA:   ck.SourceDA,
+					Entry:      entry,
+				})
 			}
 		}
 
 		c := s.currentCheckpoints[0].Checkpoint.Commitment
-		logs.Info.Printf("Checkpoints fetched from providers have been verified, the commitment: %s, current height %d, hash %s", c, height, hash)
+		logs.Info.With("height", height, "hash", hash, "commitment", c).Print("Checkpoints fetched from providers have been verified")
 		return nil
 	}
 
-	s.currentCheckpoints = cps
-	s.lastCheckpoint = s.currentCheckpoints[0]
-	s.Status.Store(int64(StatusVerified))
+	s.setCurrent(cps, height, hash)
 	c := s.currentCheckpoints[0].Checkpoint.Commitment
-	logs.Info.Printf("Checkpoints fetched from providers are all consistent: commitment=%s, height=%d, hash=%s", c, height, hash)
+	s.Publish(&Event{Type: EventCheckpoint, Height: height, Hash: hash, Commitment: c})
+	logs.Info.With("height", height, "hash", hash, "commitment", c).Print("Checkpoints fetched from providers are all consistent")
+
+	return nil
+}
+
+type succCommit struct {
+	commitment  string
+	transferLen int
+}
+
+// verifiedCommitments returns the commitment with the most Ordinals transfers to trust, and every commitment passing the
+// verification, none of which should be denied.
+func verifiedCommitments(succVerify []succCommit) (trust string, seemRight []string) {
+	champion := 0
+	seemRight = make([]string, 0, len(succVerify))
+	for i, c := range succVerify {
+		seemRight = append(seemRight, c.commitment)
+		if c.transferLen > succVerify[champion].transferLen {
+			champion = i
+		}
+	}
+	return succVerify[champion].commitment, seemRight
+}
+
+func latestStateProof(ctx context.Context, cl committee.Client, ck *checkpoint.Checkpoint) (proof *apis.Brc20VerifiableLatestStateProofResponse, err error) {
+	ctx, span := tracing.Start(
+		ctx,
+		"committee.LatestStateProof",
+		attribute.String("provider", ck.Name),
+		attribute.String("url", ck.URL),
+		attribute.String("commitment", ck.Commitment),
+	)
+	defer func() { tracing.End(span, err) }()
+	return cl.LatestStateProof(ctx)
+}
+
+// Heartbeat records that the sync loop is alive.
+func (s *State) Heartbeat() {
+	s.heartbeat.Store(time.Now().UnixNano())
+}
+
+// LastHeartbeat returns the last time the sync loop was alive.
+func (s *State) LastHeartbeat() time.Time {
+	return time.Unix(0, s.heartbeat.Load())
+}
+
+// Providers returns the number of providers answered the last checkpoint fetch, the number of all providers and the
+// minimal number of checkpoints required.
+func (s *State) Providers() (healthy, total, minimal int) {
+	ps := s.providers.Load()
+	return int(s.healthy.Load()), len(ps.providers), ps.minimalCheckpoint
+}
+
+// CheckpointProviders returns the providers in use.
+func (s *State) CheckpointProviders() []checkpoints.CheckpointProvider {
+	return s.providers.Load().providers
+}
 
+// SetProviders swaps the providers and the minimal number of checkpoints. The verified checkpoints are kept, and the
+// verification in progress keeps using the previous providers.
+func (s *State) SetProviders(providers []checkpoints.CheckpointProvider, minimalCheckpoint int) error {
+	if minimalCheckpoint < 1 {
+		return fmt.Errorf("invalid minimal checkpoint: %d", minimalCheckpoint)
+	}
+	if actual := len(providers); actual < minimalCheckpoint {
+		return fmt.Errorf("insufficient checkpoint providers: actual=%d, expected=%d", actual, minimalCheckpoint)
+	}
+	prev := s.providers.Swap(&providerSet{providers: providers, minimalCheckpoint: minimalCheckpoint})
+	logs.Info.With(
+		"providers", len(providers),
+		"previous", len(prev.providers),
+		"minimalCheckpoint", minimalCheckpoint,
+	).Print("Checkpoint providers updated")
 	return nil
 }
 
@@ -315,3 +399,43 @@ func (s *State) CurrentFirstCheckpoint() *configs.CheckpointExport {
 	}
 	return nil
 }
+
+// CheckpointAt returns the verified checkpoints at the given height from the retained history.
+func (s *State) CheckpointAt(height uint) ([]*configs.CheckpointExport, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.history) == 0 {
+		return nil, errors.New("no verified checkpoint retained yet")
+	}
+	i, found := slices.BinarySearchFunc(s.history, height, func(cks []*configs.CheckpointExport, h uint) int {
+		return cmp.Compare(checkpointHeight(cks[0]), h)
+	})
+	if !found {
+		return nil, fmt.Errorf(
+			"height not in the retained checkpoint history: height=%d, oldest=%d, latest=%d",
+			height,
+			checkpointHeight(s.history[0][0]),
+			checkpointHeight(s.history[len(s.history)-1][0]),
+		)
+	}
+	return s.history[i], nil
+}
+
+// remember records the verified checkpoints of one height into the history, checkpoints at the same or a higher
+// height are dropped as they are stale because of chain reorganization.
+func (s *State) remember(cks []*configs.CheckpointExport) {
+	if len(cks) == 0 || cks[0] == nil || cks[0].Checkpoint == nil {
+		return
+	}
+	h := checkpointHeight(cks[0])
+	s.history = slices.DeleteFunc(s.history, func(c []*configs.CheckpointExport) bool { return checkpointHeight(c[0]) >= h })
+	s.history = append(s.history, cks)
+	if over := len(s.history) - s.historySize; over > 0 {
+		s.history = slices.Delete(s.history, 0, over)
+	}
+}
+
+func checkpointHeight(ck *configs.CheckpointExport) uint {
+	h, _ := strconv.ParseUint(ck.Checkpoint.Height, 10, 64)
+	return uint(h)
+}
@@ -0,0 +1,126 @@
+package states
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+)
+
+func testCheckpoint(height uint, hash string) *configs.CheckpointExport {
+	return &configs.CheckpointExport{Checkpoint: &checkpoint.Checkpoint{
+		Height:     strconv.Itoa(int(height)),
+		Hash:       hash,
+		Commitment: "commitment-" + hash,
+	}}
+}
+
+func TestState_CheckpointAt(t *testing.T) {
+	s := New(nil, "", nil, testCheckpoint(100, "a"), 1, 0, 3)
+	for h := uint(101); h <= 103; h++ {
+		s.remember([]*configs.CheckpointExport{testCheckpoint(h, "a")})
+	}
+
+	if _, err := s.CheckpointAt(100); err == nil {
+		t.Fatal("expected the oldest checkpoint to be evicted")
+	}
+	cks, err := s.CheckpointAt(102)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cks[0].Checkpoint.Height != "102" {
+		t.Fatal(cks[0].Checkpoint)
+	}
+
+	// Reorganization at 102 drops the stale checkpoints above.
+	s.remember([]*configs.CheckpointExport{testCheckpoint(102, "b")})
+	if _, err := s.CheckpointAt(103); err == nil {
+		t.Fatal("expected the stale checkpoint to be dropped")
+	}
+	cks, err = s.CheckpointAt(102)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cks[0].Checkpoint.Hash != "b" {
+		t.Fatal(cks[0].Checkpoint)
+	}
+}
+
+func TestState_Subscribe(t *testing.T) {
+	s := New(nil, "", nil, testCheckpoint(100, "a"), 1, 0, 0)
+	events, unsubscribe := s.Subscribe()
+
+	s.setStatus(StatusVerifying, 101, "b") // Unchanged.
+	s.setStatus(StatusVerified, 101, "b")
+	e := <-events
+	if e.Type != EventStatus || e.Status != StatusVerified.String() || e.Height != 101 {
+		t.Fatal(e)
+	}
+
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Fatal("expected closed channel")
+	}
+	s.setStatus(StatusUnverified, 102, "c")
+	unsubscribe()
+}
+
+func TestState_Close(t *testing.T) {
+	s := New(nil, "", nil, testCheckpoint(100, "a"), 1, 0, 0)
+	events, unsubscribe := s.Subscribe()
+
+	s.Close()
+	if _, ok := <-events; ok {
+		t.Fatal("expected closed channel")
+	}
+	unsubscribe()
+	s.Publish(&Event{Type: EventStatus})
+
+	late, unsubscribe := s.Subscribe()
+	if _, ok := <-late; ok {
+		t.Fatal("expected closed channel after close")
+	}
+	unsubscribe()
+}
+
+func TestState_SetProviders(t *testing.T) {
+	s := New(nil, "", nil, testCheckpoint(100, "a"), 1, 0, 0)
+	s.Status.Store(int64(StatusVerified))
+
+	if err := s.SetProviders(nil, 1); err == nil {
+		t.Fatal("expected insufficient providers error")
+	}
+	providers := []checkpoints.CheckpointProvider{
+		(*configs.SourceRaw)(testCheckpoint(101, "b").Checkpoint),
+		(*configs.SourceRaw)(testCheckpoint(101, "b").Checkpoint),
+	}
+	if err := s.SetProviders(providers, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, minimal := s.Providers(); total != 2 || minimal != 2 {
+		t.Fatalf("unexpected providers: total=%d, minimal=%d", total, minimal)
+	}
+	if Status(s.Status.Load()) != StatusVerified || s.LastCheckpoint().Checkpoint.Hash != "a" {
+		t.Fatal("verified state dropped")
+	}
+}
+
+func TestVerifiedCommitments(t *testing.T) {
+	trust, seemRight := verifiedCommitments([]succCommit{{"a", 1}, {"b", 2}})
+	if trust != "b" {
+		t.Fatal("expected the commitment with the most transfers trusted", trust)
+	}
+	// Both commitments verified, the first must not be denied either.
+	for _, c := range []string{"a", "b"} {
+		if !slices.Contains(seemRight, c) {
+			t.Fatalf("verified commitment would be denied: commitment=%s, seemRight=%v", c, seemRight)
+		}
+	}
+	if trust, seemRight = verifiedCommitments([]succCommit{{"a", 3}, {"b", 2}}); trust != "a" || len(seemRight) != 2 {
+		t.Fatalf("unexpected commitments: trust=%s, seemRight=%v", trust, seemRight)
+	}
+}
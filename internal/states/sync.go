@@ -0,0 +1,89 @@
+package states
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+)
+
+const (
+	// DefaultFetchTimeout is the default timeout of fetching the checkpoints of one height from the providers.
+	DefaultFetchTimeout = 2 * time.Minute
+
+	// DefaultSyncInterval is the default interval of checking the Bitcoin tip for new blocks.
+	DefaultSyncInterval = 10 * time.Second
+)
+
+// Bootstrap fetches the consistent checkpoint of the last block from the providers, as the starting point of the
+// verification.
+func Bootstrap(
+	ctx context.Context,
+	btc *btcutl.Client,
+	providers []checkpoints.CheckpointProvider,
+	fetchTimeout time.Duration,
+) (*configs.CheckpointExport, error) {
+	currentHeight, err := btc.GetLatestBlockHeight(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest block height: %v", err)
+	}
+	lastHeight := currentHeight - 1
+	lastHash, err := btc.GetBlockHash(ctx, lastHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block hash: height=%d, err=%v", lastHeight, err)
+	}
+
+	if fetchTimeout <= 0 {
+		fetchTimeout = DefaultFetchTimeout
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	cps, err := checkpoints.GetCheckpoints(fetchCtx, providers, lastHeight, lastHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoints: height=%d, hash=%s, err=%v", lastHeight, lastHash, err)
+	}
+	if len(cps) == 0 {
+		return nil, fmt.Errorf("no checkpoints fetched: height=%d, hash=%s", lastHeight, lastHash)
+	}
+
+	// TODO: Historical verification.
+	if checkpoints.Inconsistent(cps) {
+		return nil, fmt.Errorf("inconsistent checkpoints detected at height %d, historical verification is not supported but will be released soon :'(", lastHeight)
+	}
+	return cps[0], nil
+}
+
+// Sync updates the checkpoints to the Bitcoin tip if they are not the latest, and reports whether they are updated.
+func (s *State) Sync(ctx context.Context) (bool, error) {
+	if s.btc == nil {
+		return false, errors.New("no Bitcoin RPC client")
+	}
+	s.Heartbeat()
+
+	currentHeight, err := s.btc.GetLatestBlockHeight(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get latest block height: %v", err)
+	}
+	s.progress.SetTipHeight(currentHeight)
+	currentHash, err := s.btc.GetBlockHash(ctx, currentHeight)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block hash: height=%d, err=%v", currentHeight, err)
+	}
+
+	if first := s.CurrentFirstCheckpoint(); first != nil &&
+		first.Checkpoint.Height == strconv.Itoa(int(currentHeight)) &&
+		first.Checkpoint.Hash == currentHash {
+		return false, nil
+	}
+
+	// Checkpoints are not the latest, start syncing.
+	if err := s.UpdateCheckpoints(ctx, currentHeight, currentHash); err != nil {
+		return false, fmt.Errorf("failed to update checkpoints: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone = ""
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+const (
+	ServiceName = "modular-indexer-light"
+	tracerName  = "github.com/RiemaLabs/modular-indexer-light"
+)
+
+// Options of the span exporter, zero values disable tracing.
+type Options struct {
+	Exporter string `json:"exporter,omitempty"`
+
+	// Endpoint is the host:port of the OTLP gRPC collector, empty means the OTLP default.
+	Endpoint string `json:"endpoint,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+
+	// File is the path where spans are written as JSON lines for the file exporter.
+	File string `json:"file,omitempty"`
+
+	// SampleRatio is the fraction of traces sampled, zero means all of them.
+	SampleRatio float64 `json:"sampleRatio,omitempty"`
+}
+
+// Validate checks the exporter options without connecting to the collector.
+func (o *Options) Validate() error {
+	switch o.Exporter {
+	case ExporterNone, ExporterOTLP:
+	case ExporterFile:
+		if o.File == "" {
+			return fmt.Errorf("file is required by the %q exporter", ExporterFile)
+		}
+	default:
+		return fmt.Errorf("unknown trace exporter: %q", o.Exporter)
+	}
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		return fmt.Errorf("sample ratio out of [0, 1]: %v", o.SampleRatio)
+	}
+	return nil
+}
+
+// Init installs the global tracer provider according to the options, the returned function flushes the pending spans.
+func Init(ctx context.Context, o *Options, version string) (shutdown func(context.Context) error, err error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch o.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{}
+		if o.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(o.Endpoint))
+		}
+		if o.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if exporter, err = otlptracegrpc.New(ctx, opts...); err != nil {
+			return nil, fmt.Errorf("create OTLP exporter error: endpoint=%s, err=%v", o.Endpoint, err)
+		}
+	case ExporterFile:
+		f, err := os.OpenFile(o.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open trace file error: file=%s, err=%v", o.File, err)
+		}
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(f)); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if r := o.SampleRatio; r > 0 && r < 1 {
+		sampler = sdktrace.TraceIDRatioBased(r)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(ServiceName),
+			semconv.ServiceVersionKey.String(version),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start starts a span of the global tracer, it's a no-op before Init.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit_File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Init(context.Background(), &Options{Exporter: ExporterFile, File: file}, "test")
+	require.NoError(t, err)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"parent"`)
+	assert.Contains(t, string(data), `"Name":"child"`)
+	assert.Contains(t, string(data), "boom")
+}
+
+func TestInit_Unknown(t *testing.T) {
+	_, err := Init(context.Background(), &Options{Exporter: "zipkin"}, "test")
+	assert.Error(t, err)
+}
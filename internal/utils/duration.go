@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -17,7 +18,7 @@ func (d *DurH) UnmarshalJSON(bytes []byte) error {
 	}
 	dur, err := time.ParseDuration(s)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid duration %q, expected a value like \"15s\" or \"1m30s\"", s)
 	}
 	if d != nil {
 		d.Duration = dur
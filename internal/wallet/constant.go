@@ -30,11 +30,28 @@ const (
 	USDC = ETHToken + 2
 )
 
+// AesSalt and SHA1Checksum are the constant salt and checksum key of the v1 format, only used to read v1 wallets.
 const (
 	AesSalt      = "Nubit DA Chain"
 	SHA1Checksum = "Nubit DA Chain"
 )
 
+// Version is the version of the serialization format written by ExportBinary() and ExportBase64().
+// v1 is a gzip stream checked by HMAC-SHA1 with a constant key, which is still read and upgraded on save.
+const Version = 2
+
+// Magic prefixes the v2 serialization, which never starts with the gzip header of v1.
+const Magic = "NWLT"
+
+// Scrypt parameters of the new wallets, taking about 256 MiB of memory to derive the key.
+const (
+	ScryptN = 1 << 18
+	ScryptR = 8
+	ScryptP = 1
+
+	KDFSaltLen = 32
+)
+
 const MasterSeedLen = 16
 
 const Bip39SeedLen = 64
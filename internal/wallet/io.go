@@ -13,6 +13,13 @@ import (
 
 const maxLen = 65535
 
+// Bounds of the scrypt parameters read from a serialization, so a crafted one can't exhaust the memory.
+const (
+	maxScryptN = 1 << 20
+	maxScryptR = 32
+	maxScryptP = 16
+)
+
 const tagWalletDesc = 1
 
 const tagWalletMasterSeed = 2
@@ -259,19 +266,11 @@ func (w *Wallet) writeToBuffer() []byte {
 	data := make([]byte, buf.Len())
 	copy(data, buf.Bytes())
 
-	mac := hmac.New(sha1.New, []byte(SHA1Checksum))
-	_, err := mac.Write(data)
-	if err != nil {
-		panic(err)
-	}
-	sum := mac.Sum(nil)
-
-	data = append(data, sum...)
-
 	return data
 }
 
-func (w *Wallet) readFromBuffer(buf []byte) error {
+// Reads the v1 serialization, which is checked by HMAC-SHA1 with a constant key.
+func (w *Wallet) readFromBufferV1(buf []byte) error {
 
 	// verify checksum
 	mac := hmac.New(sha1.New, []byte(SHA1Checksum))
@@ -290,6 +289,10 @@ func (w *Wallet) readFromBuffer(buf []byte) error {
 		return errors.New("reader: checksum failed")
 	}
 
+	return w.readFromBuffer(buf[:len(buf)-mac.Size()])
+}
+
+func (w *Wallet) readFromBuffer(buf []byte) error {
 	// parse buffer
 	var ac *Account
 	var as *Asset
@@ -457,17 +460,97 @@ func (w *Wallet) readFromBuffer(buf []byte) error {
 	return nil
 }
 
-func (w *Wallet) writeToBufferCompressed() []byte {
-	buf := w.writeToBuffer()
+// Writes the v2 serialization: the header of the format and the scrypt parameters, followed by the compressed
+// content sealed by AES-GCM with the wallet key, which also authenticates the header.
+func (w *Wallet) writeToBufferSealed() []byte {
+	if w.key == nil {
+		panic("wallet key not set")
+	}
 
+	header := new(bytes.Buffer)
+	header.WriteString(Magic)
+	writeTag(header, Version)
+	writeUint64(header, w.kdf.n)
+	writeUint16(header, w.kdf.r)
+	writeUint16(header, w.kdf.p)
+	writeBytes(header, w.kdf.salt)
+
+	data := header.Bytes()
+	return append(data, sealGCM(compress(w.writeToBuffer()), w.key, data)...)
+}
+
+// Reads the v2 serialization with given wallet password, see writeToBufferSealed().
+func (w *Wallet) readFromBufferSealed(buf []byte, password *string) error {
+	r := bytes.NewReader(buf)
+
+	magic := make([]byte, len(Magic))
+	if n, _ := r.Read(magic); n != len(Magic) || string(magic) != Magic {
+		return errors.New("unknown wallet format")
+	}
+	version, err := readTag(r)
+	if err != nil {
+		return err
+	}
+	if version != Version {
+		return fmt.Errorf("unsupported wallet version: %d", version)
+	}
+	if w.kdf.n, err = readUint64(r); err != nil {
+		return err
+	}
+	if w.kdf.r, err = readUint16(r); err != nil {
+		return err
+	}
+	if w.kdf.p, err = readUint16(r); err != nil {
+		return err
+	}
+	if w.kdf.salt, err = readBytes(r); err != nil {
+		return err
+	}
+
+	if w.kdf.n > maxScryptN || w.kdf.r > maxScryptR || w.kdf.p > maxScryptP {
+		return fmt.Errorf("wallet scrypt parameters too costly: n=%d, r=%d, p=%d", w.kdf.n, w.kdf.r, w.kdf.p)
+	}
+	key := w.kdf.deriveKey(password)
+	if key == nil {
+		return errors.New("invalid wallet scrypt parameters")
+	}
+	header := buf[:len(buf)-r.Len()]
+	data := openGCM(buf[len(header):], key, header)
+	if data == nil {
+		return ErrPassword
+	}
+	data, err = decompress(data)
+	if err != nil {
+		return err
+	}
+	if err := w.readFromBuffer(data); err != nil {
+		return err
+	}
+	if w.decryptMasterSeed(key) == nil {
+		return ErrPassword
+	}
+	w.setKey(key, password)
+	return nil
+}
+
+// Reads the v1 serialization, which is a compressed stream.
+func (w *Wallet) readFromBufferCompressed(buf []byte) error {
+	data, err := decompress(buf)
+	if err != nil {
+		return err
+	}
+	return w.readFromBufferV1(data)
+}
+
+func compress(buf []byte) []byte {
 	bufComp := new(bytes.Buffer)
 
-	compress, err := gzip.NewWriterLevel(bufComp, gzip.BestCompression)
+	gz, err := gzip.NewWriterLevel(bufComp, gzip.BestCompression)
 	if err != nil {
 		panic("compressing failed: " + err.Error())
 	}
 
-	n, err := compress.Write(buf)
+	n, err := gz.Write(buf)
 	if err != nil {
 		panic("compressing failed: " + err.Error())
 	}
@@ -475,7 +558,7 @@ func (w *Wallet) writeToBufferCompressed() []byte {
 		panic("compressing failed: " + err.Error())
 	}
 
-	err = compress.Close()
+	err = gz.Close()
 	if err != nil {
 		panic("compressing failed: " + err.Error())
 	}
@@ -483,12 +566,12 @@ func (w *Wallet) writeToBufferCompressed() []byte {
 	return bufComp.Bytes()
 }
 
-func (w *Wallet) readFromBufferCompressed(buf []byte) error {
+func decompress(buf []byte) ([]byte, error) {
 	r := bytes.NewReader(buf)
 
-	decompress, err := gzip.NewReader(r)
+	gz, err := gzip.NewReader(r)
 	if err != nil {
-		return errors.New("de-compressing failed: " + err.Error())
+		return nil, errors.New("de-compressing failed: " + err.Error())
 	}
 
 	blkLen := 100
@@ -497,24 +580,21 @@ func (w *Wallet) readFromBufferCompressed(buf []byte) error {
 	var result []byte
 
 	for cont := true; cont; {
-		n, err := decompress.Read(tmp)
+		n, err := gz.Read(tmp)
 		if n != 0 {
-			//fmt.Printf("Decompress tmp: %s\n", hex.EncodeToString(tmp[:n]))
 			result = append(result, tmp[:n]...)
 		} else {
 			if err == nil || err == io.EOF {
 				cont = false
 			} else {
-				return errors.New("de-compressing failed: " + err.Error())
+				return nil, errors.New("de-compressing failed: " + err.Error())
 			}
 		}
 	}
-	err = decompress.Close()
+	err = gz.Close()
 	if err != nil {
-		return errors.New("de-compressing failed: " + err.Error())
+		return nil, errors.New("de-compressing failed: " + err.Error())
 	}
 
-	//fmt.Printf("Decompress: %s\n", hex.EncodeToString(result))
-
-	return w.readFromBuffer(result)
+	return result, nil
 }
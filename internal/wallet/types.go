@@ -6,6 +6,9 @@ import (
 )
 
 type Wallet struct {
+	kdf                 kdfParams
+	key                 []byte // AES key derived from the verified password, which seals the serialization
+	keyDigest           []byte // digest of the verified password with the salt
 	desc                string
 	masterSeed          []byte
 	masterSeedLen       int
@@ -38,3 +41,11 @@ type Asset struct {
 	issuer  string
 	assetId string
 }
+
+// kdfParams are the scrypt parameters deriving the AES key of a wallet, with its random salt.
+type kdfParams struct {
+	n    uint64
+	r    uint16
+	p    uint16
+	salt []byte
+}
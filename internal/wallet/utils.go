@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"crypto/aes"
+	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha1"
@@ -12,59 +13,79 @@ import (
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
 	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
 )
 
-// Create AES key from given password string
-func deriveAesKey(password *string) (key []byte) {
+// Derives the AES key of the v1 format from given password string, with the constant salt.
+// It's only used to read and upgrade v1 wallets.
+func deriveAesKeyV1(password *string) (key []byte) {
 	return pbkdf2.Key([]byte(*password), []byte(AesSalt), 4096, 32, sha1.New)
 }
 
-// AES encrypts data with given key.
-// Data will be padded with random data to match AES block size.
-// Enncrypted data is returned in newly allocated slice. Input data will not be modified.
-func aesEncrypt(data, key []byte) []byte {
-	block, err := aes.NewCipher(key)
+// scrypt cost of the new wallets, lowered by tests.
+var scryptN uint64 = ScryptN
 
+// Creates new random scrypt parameters for a wallet.
+func newKDFParams() kdfParams {
+	salt := make([]byte, KDFSaltLen)
+	_, err := rand.Read(salt)
 	if err != nil {
 		panic(err)
 	}
+	return kdfParams{n: scryptN, r: ScryptR, p: ScryptP, salt: salt}
+}
 
-	l := len(data)
-	blockLen := block.BlockSize()
-
-	blocks := l / blockLen
-
-	padding := l % blockLen
-
-	if padding != 0 {
-		blocks += 1
+// Derives the AES key from given password string with the scrypt parameters.
+// nil is returned if the parameters are invalid.
+func (p *kdfParams) deriveKey(password *string) []byte {
+	key, err := scrypt.Key([]byte(*password), p.salt, int(p.n), int(p.r), int(p.p), 32)
+	if err != nil {
+		return nil
 	}
+	return key
+}
 
-	buf := make([]byte, blockLen*blocks)
-
-	if padding != 0 {
-		// fill padding bytes with random numbers
-		_, err = rand.Read(buf[blockLen*(blocks-1)+padding:])
-		if err != nil {
-			panic(err)
-		}
+func newGCM(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
 	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
 
-	copy(buf, data)
-
-	itr := buf
-
-	for i := 0; i < blocks; i++ {
-		block.Encrypt(itr, itr)
-		itr = itr[blockLen:]
+// Encrypts and authenticates data with given key by AES-GCM, with the additional data.
+// The random nonce is prepended to the returned ciphertext.
+func sealGCM(data, key, additional []byte) []byte {
+	aead := newGCM(key)
+	nonce := make([]byte, aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		panic(err)
 	}
+	return aead.Seal(nonce, nonce, data, additional)
+}
 
-	return buf
+// Decrypts and verifies data sealed by sealGCM with given key and additional data.
+// nil is returned if the key is wrong or the data is modified.
+func openGCM(encData, key, additional []byte) []byte {
+	aead := newGCM(key)
+	if len(encData) < aead.NonceSize() {
+		return nil
+	}
+	data, err := aead.Open(nil, encData[:aead.NonceSize()], encData[aead.NonceSize():], additional)
+	if err != nil {
+		return nil
+	}
+	return data
 }
 
-// AES descypts data with given key.
+// AES decrypts data of the v1 format with given key, block by block.
 // Input data slice is overwritten by decrypted data.
-func aesDecrypt(data, key []byte) {
+func aesDecryptV1(data, key []byte) {
 	block, err := aes.NewCipher(key)
 
 	if err != nil {
@@ -87,38 +108,21 @@ func aesDecrypt(data, key []byte) {
 	}
 }
 
-// Adds a SHA1 checksum to data and encrypts it with given key.
-func encryptWithCheckSum(data, key []byte) []byte {
-	// build checksum using a hash
-	mac := hmac.New(sha1.New, []byte(SHA1Checksum))
-	_, err := mac.Write(data)
-	if err != nil {
-		panic(err)
-	}
-	sum := mac.Sum(nil)
-	dataChk := make([]byte, len(data))
-	copy(dataChk, data)
-	dataChk = append(dataChk, sum...)
-	enc := aesEncrypt(dataChk, key)
-	return enc
-}
-
-// Decrypts data with given key and verifies SHA1 checksum.
+// Decrypts data of the v1 format with given key and verifies SHA1 checksum.
 // If verification fails nil is returned. Otherwise a newly
 // allocated slice is returned containing the decrypted data.
 // The input data is not modified.
-func decryptWithCheckSum(encData []byte, resLen int, key []byte) []byte {
+func decryptWithCheckSumV1(encData []byte, resLen int, key []byte) []byte {
 	buf := make([]byte, len(encData))
 	copy(buf, encData)
 
-	aesDecrypt(buf, key)
-
-	if len(buf) < resLen {
-		return nil
-	}
+	aesDecryptV1(buf, key)
 
 	// check checksum using a hash
 	mac := hmac.New(sha1.New, []byte(SHA1Checksum))
+	if len(buf) < resLen+mac.Size() {
+		return nil
+	}
 	_, err := mac.Write(buf[:resLen])
 	if err != nil {
 		panic(err)
@@ -3,6 +3,7 @@ package wallet
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"strings"
@@ -14,52 +15,106 @@ import (
 	"github.com/RiemaLabs/modular-indexer-light/internal/utils"
 )
 
+// ErrPassword is returned when importing a wallet with a wrong password.
+var ErrPassword = errors.New("wrong wallet password or corrupted wallet")
+
 // NewWallet creates a new empty wallet, encrypted with given password.
-// Each new wallet has an associated encrypted 256 bit entropy, which is the source for the mnemonic words list,
+// Each new wallet has an associated encrypted 128 bit entropy, which is the source for the mnemonic words list,
 // i.e. the mnemonic word list is defined when a new wallet is created.
 func NewWallet(password *string) *Wallet {
-	wallet := new(Wallet)
 	entropy, err := bip39.NewEntropy(128)
 	if err != nil {
 		panic(err)
 	}
-	key := deriveAesKey(password)
+	return newWalletFromEntropy(entropy, password)
+}
+
+func newWalletFromEntropy(entropy []byte, password *string) *Wallet {
+	wallet := &Wallet{kdf: newKDFParams()}
+	key := wallet.deriveKey(password)
 	wallet.encryptMasterSeed(entropy, key)
+	wallet.setKey(key, password)
 	return wallet
 }
 
-// ImportBinary creates a new wallet from an exported binary serialization of the wallet content.
-// This method can be used to restore a wallet from a permanent storage location.
-// This method panics if the build-in self test fails (see method SelfTest()).
-func ImportBinary(buf []byte) (w *Wallet, err error) {
+// ImportMnemonic creates a new wallet from the mnemonic word list of another one, encrypted with given password.
+// The BIP39 seed must be generated again by GenerateBip39Seed() with the same mnemonic password to derive the same
+// accounts.
+func ImportMnemonic(words []string, password *string) (*Wallet, error) {
+	entropy, err := bip39.EntropyFromMnemonic(strings.Join(words, " "))
+	if err != nil {
+		return nil, errors.New("invalid mnemonic: " + err.Error())
+	}
+	if len(entropy) != MasterSeedLen {
+		return nil, errors.New("unsupported mnemonic length, 12 words expected")
+	}
+	return newWalletFromEntropy(entropy, password), nil
+}
+
+// ImportBinary creates a new wallet from an exported binary serialization of the wallet content, decrypted with given
+// wallet password. This method can be used to restore a wallet from a permanent storage location.
+// A v1 serialization is upgraded in memory, i.e. it's written in the current format by the next export.
+// ErrPassword is returned if the wallet password is not valid.
+func ImportBinary(buf []byte, password *string) (w *Wallet, err error) {
 	w = new(Wallet)
-	err = w.readFromBufferCompressed(buf)
+	if IsV1(buf) {
+		err = w.readFromBufferCompressed(buf)
+		if err == nil {
+			err = w.upgradeV1(password)
+		}
+	} else {
+		err = w.readFromBufferSealed(buf, password)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return w, nil
 }
 
-// ImportBase64 creates a new wallet from an exported ascii (base 64) serialization of the wallet content.
-// This method can be used to restore a wallet from a permanent storage location.
-// This method panics if the build-in self test fails (see method SelfTest()).
-func ImportBase64(data string) (w *Wallet, err error) {
+// ImportBase64 creates a new wallet from an exported ascii (base 64) serialization of the wallet content, decrypted
+// with given wallet password. See ImportBinary().
+func ImportBase64(data string, password *string) (w *Wallet, err error) {
 	buf, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
 		return nil, errors.New("base64 decoding failed: " + err.Error())
 	}
-	w = new(Wallet)
-	err = w.readFromBufferCompressed(buf)
-	if err != nil {
-		return nil, err
+	return ImportBinary(buf, password)
+}
+
+// IsV1 checks if given binary serialization is in the v1 format, which is a gzip stream.
+func IsV1(buf []byte) bool {
+	return len(buf) >= 2 && buf[0] == 0x1f && buf[1] == 0x8b
+}
+
+// Re-encrypts the seeds of a v1 wallet, which were encrypted with the constant salt, with a new random salt.
+func (w *Wallet) upgradeV1(password *string) error {
+	keyV1 := deriveAesKeyV1(password)
+	masterSeed := decryptWithCheckSumV1(w.masterSeed, MasterSeedLen, keyV1)
+	if masterSeed == nil {
+		return ErrPassword
 	}
-	return w, nil
+	var bip39Seed []byte
+	if w.bip39Seed != nil {
+		bip39Seed = decryptWithCheckSumV1(w.bip39Seed, Bip39SeedLen, keyV1)
+		if bip39Seed == nil {
+			return ErrPassword
+		}
+	}
+
+	w.kdf = newKDFParams()
+	key := w.deriveKey(password)
+	w.encryptMasterSeed(masterSeed, key)
+	if bip39Seed != nil {
+		w.encryptBip39Seed(bip39Seed, key)
+	}
+	w.setKey(key, password)
+	return nil
 }
 
 // Bip39Mnemonic returns mnemonic word list (24 words) associated with the current wallet.
 // After creating a new wallet this word list should be presented to the user.
 func (w *Wallet) Bip39Mnemonic(walletPassword *string) (words []string) {
-	key := deriveAesKey(walletPassword)
+	key := w.deriveKey(walletPassword)
 	words = w.getBip39Mnemonic(key)
 	return
 }
@@ -104,7 +159,7 @@ func (w *Wallet) GenerateBip39Seed(walletPassword *string, mnemonicPassword *str
 	if w.bip39Seed != nil {
 		return false
 	}
-	key := deriveAesKey(walletPassword)
+	key := w.deriveKey(walletPassword)
 	words := w.getBip39Mnemonic(key)
 	if words == nil {
 		// may happen if wallet password is not correct
@@ -168,27 +223,62 @@ func (w *Wallet) newAccount() *Account {
 }
 
 func (w *Wallet) encryptBip39Seed(seed, key []byte) {
-	w.bip39Seed = encryptWithCheckSum(seed, key)
+	w.bip39Seed = sealGCM(seed, key, []byte{tagWalletBip39Seed})
 }
 
 func (w *Wallet) decryptBip39Seed(key []byte) []byte {
-	return decryptWithCheckSum(w.bip39Seed, Bip39SeedLen, key)
+	seed := openGCM(w.bip39Seed, key, []byte{tagWalletBip39Seed})
+	if len(seed) != Bip39SeedLen {
+		return nil
+	}
+	return seed
 }
 
 func (w *Wallet) encryptMasterSeed(seed, key []byte) {
-	w.masterSeed = encryptWithCheckSum(seed, key)
+	w.masterSeed = sealGCM(seed, key, []byte{tagWalletMasterSeed})
 }
 
 func (w *Wallet) decryptMasterSeed(key []byte) []byte {
-	return decryptWithCheckSum(w.masterSeed, MasterSeedLen, key)
+	seed := openGCM(w.masterSeed, key, []byte{tagWalletMasterSeed})
+	if len(seed) != MasterSeedLen {
+		return nil
+	}
+	return seed
+}
+
+// Derives the AES key from given password with the scrypt parameters of the wallet.
+// The key of the verified password is reused, so the memory-hard derivation runs once per wallet.
+// nil is returned if the scrypt parameters are invalid.
+func (w *Wallet) deriveKey(walletPassword *string) []byte {
+	if w.key != nil && bytes.Equal(w.keyDigest, w.passwordDigest(walletPassword)) {
+		return w.key
+	}
+	return w.kdf.deriveKey(walletPassword)
+}
+
+// Keeps the AES key of the verified password, which seals the serialization on export.
+func (w *Wallet) setKey(key []byte, walletPassword *string) {
+	w.key = key
+	w.keyDigest = w.passwordDigest(walletPassword)
+}
+
+func (w *Wallet) passwordDigest(walletPassword *string) []byte {
+	h := sha256.New()
+	h.Write(w.kdf.salt)
+	h.Write([]byte(*walletPassword))
+	return h.Sum(nil)
 }
 
 // Checks if given wallet password is valid.
 // Returns derived AES key on success else nil
 func (w *Wallet) checkPassword(walletPassword *string) []byte {
-	key := deriveAesKey(walletPassword)
+	key := w.deriveKey(walletPassword)
+	if key == nil {
+		return nil
+	}
 	seed := w.decryptMasterSeed(key)
 	if seed != nil {
+		w.setKey(key, walletPassword)
 		return key
 	}
 	return nil
@@ -204,13 +294,15 @@ func (w *Wallet) CheckPassword(walletPassword *string) bool {
 }
 
 // ExportBinary creates a binary serialization of the wallet content, e.g. for permanent storage of the wallet on disk.
+// It's encrypted and authenticated as a whole with the key of the wallet password, in the current format.
 func (w *Wallet) ExportBinary() []byte {
-	return w.writeToBufferCompressed()
+	return w.writeToBufferSealed()
 }
 
 // ExportBase64 creates an ascii (base 64) serialization of the wallet content, e.g. for permanent storage of the wallet on disk.
+// See ExportBinary().
 func (w *Wallet) ExportBase64() string {
-	buf := w.writeToBufferCompressed()
+	buf := w.writeToBufferSealed()
 
 	if buf == nil {
 		return ""
@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/utils"
+)
+
+// walletV1 is a wallet exported in the v1 format with the password "secret".
+const walletV1 = "H4sIAAAAAAAC/wCyAE3/ZAEAAAIAMMq4q1xCykgM+cCjELusvqrQzFLHEehXR09M3/UehkhOP0j1T3VE9fhRSR8Vnth/3QMAYOP3PvAb5fk6MM4qxc/qqcbRq08bKEZwhYNb3TqZwJRmKw3i/L9psWGocm+NGRj2+8cOOY9iSSGdAM6jdi9D9d4676PDpyAnd2Es0KCut6PXmuRXum8AKvyuoYHOEcgSrwQAAGU1iZrscAnxoJI2N+LgEl3wQnMj7AMA7duhXLIAAAA="
+
+const (
+	walletV1Mnemonic = "globe destroy riot behave burst canyon lizard outside blame win brand open"
+	walletV1Key      = "07c6aafa86b695527a22c22216e8439569865d680ae3a760f87af04335e517b0"
+)
+
+func TestImportBase64(t *testing.T) {
+	scryptN = 1 << 10
+	defer func() { scryptN = ScryptN }()
+
+	pwd, wrong := "secret", "wrong"
+	if _, err := ImportBase64(walletV1, &wrong); !errors.Is(err, ErrPassword) {
+		t.Fatal("expected wrong password", err)
+	}
+	w, err := ImportBase64(walletV1, &pwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Upgraded on export.
+	buf := w.ExportBinary()
+	if IsV1(buf) || !bytes.HasPrefix(buf, []byte(Magic)) || bytes.Contains(buf, w.masterSeed) {
+		t.Fatal("expected the v2 format")
+	}
+	if _, err := ImportBinary(buf, &wrong); !errors.Is(err, ErrPassword) {
+		t.Fatal("expected wrong password", err)
+	}
+	tampered := bytes.Clone(buf)
+	tampered[len(Magic)+1] ^= 1
+	if _, err := ImportBinary(tampered, &pwd); err == nil {
+		t.Fatal("expected the tampered header rejected")
+	}
+
+	if w, err = ImportBinary(buf, &pwd); err != nil {
+		t.Fatal(err)
+	}
+	if mnemonic := strings.Join(w.Bip39Mnemonic(&pwd), " "); mnemonic != walletV1Mnemonic {
+		t.Fatal("unexpected mnemonic", mnemonic)
+	}
+	if key := utils.EcdsaToPrivateStr(w.GenerateAccount(&pwd).PrivateKey(&pwd)); key != walletV1Key {
+		t.Fatal("unexpected private key", key)
+	}
+}
+
+func TestImportMnemonic(t *testing.T) {
+	scryptN = 1 << 10
+	defer func() { scryptN = ScryptN }()
+
+	pwd, empty := "other", ""
+	w, err := ImportMnemonic(strings.Fields(walletV1Mnemonic), &pwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.GenerateBip39Seed(&pwd, &empty) {
+		t.Fatal("failed to generate BIP39 seed")
+	}
+	if key := utils.EcdsaToPrivateStr(w.GenerateAccount(&pwd).PrivateKey(&pwd)); key != walletV1Key {
+		t.Fatal("unexpected private key", key)
+	}
+}
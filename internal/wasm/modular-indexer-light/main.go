@@ -3,12 +3,14 @@
 package main
 
 import (
+	"context"
 	"syscall/js"
 	"time"
 
 	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
 
 	"github.com/RiemaLabs/modular-indexer-light/internal/apps"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/httputl"
 	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
 	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
 	"github.com/RiemaLabs/modular-indexer-light/internal/services"
@@ -63,7 +65,11 @@ func SetConfig(_ js.Value, args []js.Value) any {
 }
 
 func Initialize(js.Value, []js.Value) any {
-	go app.Run()
+	go func() {
+		if err := app.Run(context.Background()); err != nil {
+			logs.Error.Printf("Light indexer exit with error: %v", err)
+		}
+	}()
 	return nil
 }
 
@@ -77,7 +83,8 @@ func Warmup(js.Value, []js.Value) any {
 				continue
 			}
 			_, _ = services.GetCurrentBalanceOfWallet(
-				states.S.CurrentFirstCheckpoint().Checkpoint,
+				httputl.TODO(),
+				currentCheckpoints(),
 				"ordi",
 				"bc1qhuv3dhpnm0wktasd3v0kt6e4aqfqsd0uhfdu7d",
 			)
@@ -106,6 +113,14 @@ func isVerifying() bool {
 	return states.S == nil || states.Status(states.S.Status.Load()) != states.StatusVerified
 }
 
+func currentCheckpoints() []*checkpoint.Checkpoint {
+	var ret []*checkpoint.Checkpoint
+	for _, export := range states.S.CurrentCheckpoints() {
+		ret = append(ret, export.Checkpoint)
+	}
+	return ret
+}
+
 func GetBlockHeight(js.Value, []js.Value) any {
 	return Promise.New(js.FuncOf(func(_ js.Value, args []js.Value) any {
 		resolve := args[0]
@@ -132,7 +147,7 @@ func GetCurrentBalanceOfPkScript(_ js.Value, args []js.Value) any {
 		}
 
 		go func() {
-			balance, err := services.GetCurrentBalanceOfPkscript(states.S.CurrentFirstCheckpoint().Checkpoint, tick, pkscript)
+			balance, err := services.GetCurrentBalanceOfPkscript(httputl.TODO(), currentCheckpoints(), tick, pkscript)
 			if err != nil {
 				reject.Invoke(Error.New(err.Error()))
 				return
@@ -157,7 +172,7 @@ func GetCurrentBalanceOfWallet(_ js.Value, args []js.Value) any {
 		}
 
 		go func() {
-			balance, err := services.GetCurrentBalanceOfWallet(states.S.CurrentFirstCheckpoint().Checkpoint, tick, wallet)
+			balance, err := services.GetCurrentBalanceOfWallet(httputl.TODO(), currentCheckpoints(), tick, wallet)
 			if err != nil {
 				reject.Invoke(Error.New(err.Error()))
 				return
@@ -176,11 +191,7 @@ func GetCurrentCheckpoints(js.Value, []js.Value) any {
 		if isVerifying() {
 			reject.Invoke(Error.New("light Indexer still verifying"))
 		} else {
-			var ret []*checkpoint.Checkpoint
-			for _, export := range states.S.CurrentCheckpoints() {
-				ret = append(ret, export.Checkpoint)
-			}
-			resolve.Invoke(utils.Raw[utils.RawSlice](ret))
+			resolve.Invoke(utils.Raw[utils.RawSlice](currentCheckpoints()))
 		}
 		return nil
 	}))
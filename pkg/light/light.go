@@ -0,0 +1,381 @@
+// Package light embeds the Nubit light indexer into Go programs.
+//
+// A Node verifies the checkpoints of the committee indexers against the Bitcoin chain and serves verified balances.
+// Several nodes, e.g. of different meta-protocols or networks, could run in one process, each with its own state and
+// verification progress gauges. They still share the process-wide parts of the daemon:
+//   - the loggers;
+//   - the metrics of the provider fetches, RPC calls, conflicts and balance verifications in the default Prometheus
+//     registry, mixing the nodes;
+//   - the latency ranking of the committee indexers, keyed by URL;
+//   - the balances retained for historical heights, keyed by commitment.
+package light
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/apis"
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/btcutl"
+	"github.com/RiemaLabs/modular-indexer-light/internal/clients/jsonrpc"
+	"github.com/RiemaLabs/modular-indexer-light/internal/configs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/logs"
+	"github.com/RiemaLabs/modular-indexer-light/internal/metrics"
+	"github.com/RiemaLabs/modular-indexer-light/internal/services"
+	"github.com/RiemaLabs/modular-indexer-light/internal/states"
+)
+
+type (
+	// RPC is the JSON-RPC backend of the Bitcoin node.
+	RPC = jsonrpc.Client
+
+	// Provider provides the checkpoints of a committee indexer.
+	Provider = checkpoints.CheckpointProvider
+
+	// Checkpoint is a checkpoint with the source it comes from.
+	Checkpoint = configs.CheckpointExport
+
+	SourceS3 = configs.SourceS3
+	SourceDA = configs.SourceDA
+
+	Event     = states.Event
+	EventType = states.EventType
+	Balance   = states.Balance
+)
+
+const (
+	EventStatus     = states.EventStatus
+	EventCheckpoint = states.EventCheckpoint
+	EventConflict   = states.EventConflict
+	EventDenial     = states.EventDenial
+)
+
+var (
+	ErrNotStarted = errors.New("light indexer not started")
+	ErrStarted    = errors.New("light indexer already started")
+	ErrUnverified = errors.New("light indexer state not verified")
+
+	// ErrNotRetained is returned for a balance at a historical height that wasn't queried while the height was the
+	// current one, or a height out of the retained checkpoints.
+	ErrNotRetained = services.ErrNotRetained
+)
+
+// NewProviderS3 creates the provider of a committee indexer publishing checkpoints to S3, trusting the Signers of the
+// Config of the node it's used by.
+func NewProviderS3(source *SourceS3, metaProtocol string) Provider {
+	return checkpoints.NewProviderS3(source, metaProtocol)
+}
+
+// NewProviderDA creates the provider of a committee indexer publishing checkpoints to DA.
+func NewProviderDA(source *SourceDA, metaProtocol string) Provider {
+	return checkpoints.NewProviderDA(source, metaProtocol)
+}
+
+// Config of a Node, zero values mean the defaults.
+type Config struct {
+	// RPC is the Bitcoin RPC backend, a JSON-RPC client of BitcoinRPC is created if it's nil.
+	RPC        RPC
+	BitcoinRPC string
+
+	Providers []Provider
+
+	// MinimalCheckpoint is the minimal number of checkpoints fetched at a height to verify it.
+	MinimalCheckpoint int
+
+	// Signers is the hex of the x-only public keys trusted to sign the checkpoints of the S3 providers, empty to also
+	// accept the unsigned checkpoints and those signed by any key.
+	Signers []string
+
+	// DenyListPath is the file where the evidences of fraud providers are appended, empty disables the deny list.
+	DenyListPath string
+
+	FetchTimeout time.Duration
+	SyncInterval time.Duration
+
+	// HistorySize is the number of verified checkpoints retained for CheckpointAt.
+	HistorySize int
+
+	// Registerer registers the gauges of the verification progress of the node, such as the verification status and
+	// the verified height. Nodes in one process must use different registerers, nil leaves the gauges unregistered.
+	Registerer prometheus.Registerer
+}
+
+// Status of a Node.
+type Status struct {
+	State      string `json:"state"`
+	Verified   bool   `json:"verified"`
+	Height     uint   `json:"height"`
+	Hash       string `json:"hash,omitempty"`
+	Commitment string `json:"commitment,omitempty"`
+}
+
+// Node is a light indexer.
+type Node struct {
+	cfg      Config
+	btc      *btcutl.Client
+	progress *metrics.Verification
+
+	state  *states.State
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	sync.RWMutex
+}
+
+func New(cfg Config) (*Node, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, errors.New("no checkpoint providers")
+	}
+	if cfg.MinimalCheckpoint <= 0 {
+		cfg.MinimalCheckpoint = 1
+	}
+	if actual, expected := len(cfg.Providers), cfg.MinimalCheckpoint; actual < expected {
+		return nil, fmt.Errorf("insufficient checkpoint providers: actual=%d, expected=%d", actual, expected)
+	}
+	if cfg.FetchTimeout <= 0 {
+		cfg.FetchTimeout = states.DefaultFetchTimeout
+	}
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = states.DefaultSyncInterval
+	}
+
+	rpc := cfg.RPC
+	if rpc == nil {
+		if cfg.BitcoinRPC == "" {
+			return nil, errors.New("either RPC or BitcoinRPC is required")
+		}
+		var err error
+		if rpc, err = jsonrpc.New(cfg.BitcoinRPC); err != nil {
+			return nil, err
+		}
+	}
+	progress, err := metrics.NewVerification(cfg.Registerer)
+	if err != nil {
+		return nil, fmt.Errorf("register metrics error: err=%v", err)
+	}
+	cfg.applySigners(cfg.Providers)
+	return &Node{cfg: cfg, btc: btcutl.NewWithRPC(rpc), progress: progress}, nil
+}
+
+// applySigners sets the trusted signers of the S3 providers, the other providers don't sign checkpoints.
+func (c *Config) applySigners(providers []Provider) {
+	if len(c.Signers) == 0 {
+		return
+	}
+	for _, p := range providers {
+		if p, ok := p.(*checkpoints.S3); ok {
+			p.Signers = c.Signers
+		}
+	}
+}
+
+// Start syncs the latest consistent checkpoint from the providers, then keeps verifying the checkpoints of new blocks
+// in the background until the context is done or the node is closed.
+func (n *Node) Start(ctx context.Context) error {
+	n.Lock()
+	defer n.Unlock()
+	if n.state != nil {
+		return ErrStarted
+	}
+
+	lastCheckpoint, err := states.Bootstrap(ctx, n.btc, n.cfg.Providers, n.cfg.FetchTimeout)
+	if err != nil {
+		return err
+	}
+	n.state = states.New(
+		n.btc,
+		n.cfg.DenyListPath,
+		n.cfg.Providers,
+		lastCheckpoint,
+		n.cfg.MinimalCheckpoint,
+		n.cfg.FetchTimeout,
+		n.cfg.HistorySize,
+	)
+	n.state.SetMetrics(n.progress)
+
+	ctx, n.cancel = context.WithCancel(ctx)
+	n.done = make(chan struct{})
+	go n.run(ctx)
+	return nil
+}
+
+func (n *Node) run(ctx context.Context) {
+	defer close(n.done)
+	for {
+		if _, err := n.state.Sync(ctx); err != nil && ctx.Err() == nil {
+			logs.Error.Printf("Failed to sync latest state: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(n.cfg.SyncInterval):
+		}
+	}
+}
+
+// Close stops the background verification and ends the event subscriptions.
+func (n *Node) Close() {
+	n.Lock()
+	defer n.Unlock()
+	if n.state == nil {
+		return
+	}
+	n.cancel()
+	<-n.done
+	n.state.Close()
+}
+
+// SetProviders swaps the providers of a started node, the verified checkpoints are kept and the new providers are used
+// from the next block. The S3 providers trust the Signers of the Config.
+func (n *Node) SetProviders(providers []Provider, minimalCheckpoint int) error {
+	s, err := n.getState()
+	if err != nil {
+		return err
+	}
+	if minimalCheckpoint <= 0 {
+		minimalCheckpoint = 1
+	}
+	n.cfg.applySigners(providers)
+	return s.SetProviders(providers, minimalCheckpoint)
+}
+
+func (n *Node) getState() (*states.State, error) {
+	n.RLock()
+	defer n.RUnlock()
+	if n.state == nil {
+		return nil, ErrNotStarted
+	}
+	return n.state, nil
+}
+
+func (n *Node) Status() *Status {
+	s, err := n.getState()
+	if err != nil {
+		return &Status{State: states.StatusVerifying.String()}
+	}
+	status := states.Status(s.Status.Load())
+	ret := &Status{State: status.String(), Verified: status == states.StatusVerified}
+	if ck := s.CurrentFirstCheckpoint(); ck != nil {
+		ret.Height = s.CurrentHeight()
+		ret.Hash = ck.Checkpoint.Hash
+		ret.Commitment = ck.Checkpoint.Commitment
+	}
+	return ret
+}
+
+// Checkpoints returns the verified checkpoints at the current height.
+func (n *Node) Checkpoints() ([]*Checkpoint, error) {
+	s, err := n.getState()
+	if err != nil {
+		return nil, err
+	}
+	return s.CurrentCheckpoints(), nil
+}
+
+// CheckpointAt returns the verified checkpoints at a retained historical height.
+func (n *Node) CheckpointAt(height uint) ([]*Checkpoint, error) {
+	s, err := n.getState()
+	if err != nil {
+		return nil, err
+	}
+	return s.CheckpointAt(height)
+}
+
+// Balance returns the balance of the wallet at the current height, verified against the commitment.
+func (n *Node) Balance(ctx context.Context, tick, wallet string) (*Balance, error) {
+	return n.BalanceAt(ctx, 0, tick, wallet)
+}
+
+// BalanceAt returns the balance of the wallet at the height, zero means the current height. The committee indexers only
+// prove balances against their current state, so a balance at a historical height is the one verified when the height
+// was the current one, or ErrNotRetained if it wasn't queried then.
+func (n *Node) BalanceAt(ctx context.Context, height uint, tick, wallet string) (*Balance, error) {
+	cks, current, err := n.checkpointsAt(height)
+	if err != nil {
+		return nil, err
+	}
+	var rsp *apis.Brc20VerifiableCurrentBalanceOfWalletResponse
+	if current {
+		rsp, err = services.GetCurrentBalanceOfWallet(ctx, cks, tick, wallet)
+	} else {
+		rsp, err = services.GetRetainedBalanceOfWallet(cks, tick, wallet)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret := &Balance{AvailableBalance: "0", OverallBalance: "0"}
+	if r := rsp.Result; r != nil {
+		ret.AvailableBalance = r.AvailableBalance
+		ret.OverallBalance = r.OverallBalance
+	}
+	return ret, nil
+}
+
+// BalanceOfPkscript returns the balance of the pkscript at the current height, verified against the commitment.
+func (n *Node) BalanceOfPkscript(ctx context.Context, tick, pkscript string) (*Balance, error) {
+	return n.BalanceOfPkscriptAt(ctx, 0, tick, pkscript)
+}
+
+// BalanceOfPkscriptAt is like BalanceAt but queries by pkscript.
+func (n *Node) BalanceOfPkscriptAt(ctx context.Context, height uint, tick, pkscript string) (*Balance, error) {
+	cks, current, err := n.checkpointsAt(height)
+	if err != nil {
+		return nil, err
+	}
+	var rsp *apis.Brc20VerifiableCurrentBalanceOfPkscriptResponse
+	if current {
+		rsp, err = services.GetCurrentBalanceOfPkscript(ctx, cks, tick, pkscript)
+	} else {
+		rsp, err = services.GetRetainedBalanceOfPkscript(cks, tick, pkscript)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret := &Balance{AvailableBalance: "0", OverallBalance: "0"}
+	if r := rsp.Result; r != nil {
+		ret.AvailableBalance = r.AvailableBalance
+		ret.OverallBalance = r.OverallBalance
+	}
+	return ret, nil
+}
+
+// checkpointsAt returns the verified checkpoints at the height, and whether they're the current ones.
+func (n *Node) checkpointsAt(height uint) ([]*checkpoint.Checkpoint, bool, error) {
+	s, err := n.getState()
+	if err != nil {
+		return nil, false, err
+	}
+	if states.Status(s.Status.Load()) != states.StatusVerified {
+		return nil, false, ErrUnverified
+	}
+	exports := s.CurrentCheckpoints()
+	current := height == 0 || strconv.FormatUint(uint64(height), 10) == exports[0].Checkpoint.Height
+	if !current {
+		if exports, err = s.CheckpointAt(height); err != nil {
+			return nil, false, fmt.Errorf("%w: %v", ErrNotRetained, err)
+		}
+	}
+	cks := make([]*checkpoint.Checkpoint, 0, len(exports))
+	for _, ck := range exports {
+		cks = append(cks, ck.Checkpoint)
+	}
+	return cks, current, nil
+}
+
+// Subscribe returns the channel of the state events and the function to unsubscribe. Slow subscribers miss events
+// rather than blocking the verification.
+func (n *Node) Subscribe() (<-chan *Event, func(), error) {
+	s, err := n.getState()
+	if err != nil {
+		return nil, nil, err
+	}
+	events, unsubscribe := s.Subscribe()
+	return events, unsubscribe, nil
+}
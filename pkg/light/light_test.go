@@ -0,0 +1,156 @@
+package light
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RiemaLabs/modular-indexer-committee/checkpoint"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/RiemaLabs/modular-indexer-light/internal/checkpoints"
+)
+
+type fakeRPC struct{ tip uint }
+
+func (f *fakeRPC) Call(_ context.Context, method string, params, out any) error {
+	var result any
+	switch method {
+	case "getblockcount":
+		result = f.tip
+	case "getblockhash":
+		result = fmt.Sprintf("hash-%d", params.([]uint)[0])
+	default:
+		return fmt.Errorf("unexpected method: %s", method)
+	}
+	data, err := json.Marshal(map[string]any{"result": result})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// fakeProvider provides the checkpoints of any height with the commitment.
+type fakeProvider string
+
+func (p fakeProvider) Get(_ context.Context, height uint, hash string) (*Checkpoint, error) {
+	return &Checkpoint{Checkpoint: &checkpoint.Checkpoint{
+		Height:     strconv.Itoa(int(height)),
+		Hash:       hash,
+		Commitment: string(p),
+	}}, nil
+}
+
+func TestNode(t *testing.T) {
+	newNode := func(tip uint, commitment string, reg prometheus.Registerer) *Node {
+		n, err := New(Config{
+			RPC:               &fakeRPC{tip: tip},
+			Providers:         []Provider{fakeProvider(commitment), fakeProvider(commitment)},
+			MinimalCheckpoint: 2,
+			SyncInterval:      10 * time.Millisecond,
+			Registerer:        reg,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	regA, regB := prometheus.NewRegistry(), prometheus.NewRegistry()
+	a, b := newNode(100, "commitment-a", regA), newNode(200, "commitment-b", regB)
+
+	if _, err := a.Checkpoints(); err != ErrNotStarted {
+		t.Fatal(err)
+	}
+	for _, n := range []*Node{a, b} {
+		if err := n.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer n.Close()
+	}
+	if err := a.Start(context.Background()); err != ErrStarted {
+		t.Fatal(err)
+	}
+
+	waitVerified := func(n *Node) *Status {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if s := n.Status(); s.Verified {
+				return s
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("node not verified")
+		return nil
+	}
+	if s := waitVerified(a); s.Height != 100 || s.Hash != "hash-100" || s.Commitment != "commitment-a" {
+		t.Fatal(s)
+	}
+	if s := waitVerified(b); s.Height != 200 || s.Hash != "hash-200" || s.Commitment != "commitment-b" {
+		t.Fatal(s)
+	}
+
+	gauge := func(reg *prometheus.Registry, name string) float64 {
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() == name {
+				return mf.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		t.Fatal("gauge not found:", name)
+		return 0
+	}
+	if h := gauge(regA, "light_indexer_verified_height"); h != 100 {
+		t.Fatal("unexpected verified height of a:", h)
+	}
+	if h := gauge(regB, "light_indexer_verified_height"); h != 200 {
+		t.Fatal("unexpected verified height of b:", h)
+	}
+
+	cks, err := a.CheckpointAt(99)
+	if err != nil || len(cks) != 1 || cks[0].Checkpoint.Commitment != "commitment-a" {
+		t.Fatal(cks, err)
+	}
+
+	if _, err := a.BalanceAt(context.Background(), 50, "ordi", "wallet"); !errors.Is(err, ErrNotRetained) {
+		t.Fatal("expected the height out of the history not retained", err)
+	}
+
+	if err := a.SetProviders([]Provider{fakeProvider("commitment-a")}, 2); err == nil {
+		t.Fatal("expected insufficient providers error")
+	}
+	if err := a.SetProviders([]Provider{fakeProvider("commitment-a")}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if s := a.Status(); !s.Verified || s.Height != 100 {
+		t.Fatal("verified state dropped", s)
+	}
+
+	events, unsubscribe, err := b.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	b.Close()
+	for range events {
+		// Drain until closed.
+	}
+}
+
+func TestNew_Signers(t *testing.T) {
+	signers := []string{"2b1c3d7e0d8f5a8a4e4b7c7b7a1f3a7f2f6b5e9c1d0a3b4c5d6e7f8091a2b3c4"}
+	s3 := NewProviderS3(&SourceS3{Name: "s3"}, "brc-20")
+	if _, err := New(Config{RPC: &fakeRPC{}, Providers: []Provider{s3, fakeProvider("commitment")}, Signers: signers}); err != nil {
+		t.Fatal(err)
+	}
+	if actual := s3.(*checkpoints.S3).Signers; !slices.Equal(actual, signers) {
+		t.Fatalf("signers not applied: expected=%v, actual=%v", signers, actual)
+	}
+}